@@ -1,6 +1,6 @@
 module github.com/cultureamp/glamplify
 
-go 1.14
+go 1.16
 
 require (
 	github.com/aws/aws-lambda-go v1.13.2
@@ -16,5 +16,8 @@ require (
 	github.com/newrelic/go-agent v2.14.1+incompatible
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/spf13/viper v1.4.0
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
 	gotest.tools v2.2.0+incompatible
 )
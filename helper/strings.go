@@ -3,10 +3,15 @@ package helper
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 func ToSnakeCase(s string) string {
 
+	if isSnakeCase(s) {
+		return s
+	}
+
 	var sb strings.Builder
 
 	in := []rune(strings.TrimSpace(s))
@@ -29,3 +34,18 @@ func ToSnakeCase(s string) string {
 
 	return sb.String()
 }
+
+// isSnakeCase returns true if ToSnakeCase would return s unchanged, so the common case doesn't allocate
+func isSnakeCase(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf || ('A' <= c && c <= 'Z') {
+			return false
+		}
+		switch c {
+		case ' ', '\t', '\n', '\v', '\f', '\r':
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,295 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy controls what an AsyncWriter does when its buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room in the buffer (default)
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the entry being written
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued entry to make room for the new one
+	OverflowDropOldest
+)
+
+// AsyncWriterConfig for setting initial values for AsyncWriter
+type AsyncWriterConfig struct {
+	Output     io.Writer
+	BufferSize int
+	Overflow   OverflowPolicy
+
+	// Encoder formats each entry, see WriterConfig.Encoder
+	Encoder Encoder
+}
+
+// AsyncWriter serializes entries on the calling go routine and writes them to the output on a background go routine.
+// Call Flush or Close before the process exits otherwise queued entries will be lost.
+type AsyncWriter struct {
+	output   io.Writer
+	encoder  Encoder
+	overflow OverflowPolicy
+
+	// sink, if set, is given each entry's fields on the background go routine instead of output getting the encoded
+	// line. MultiWriter uses this to run each of its sinks.
+	sink    Writer
+	onPanic func(recovered interface{})
+
+	entries chan asyncEntry
+	flushes chan chan struct{}
+	stop    chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+
+	// enqueuing is held for reading while an entry is queued, so close can wait for those in flight before the
+	// background go routine drains the queue for the last time
+	enqueuing sync.RWMutex
+
+	closeOnce sync.Once
+	dropped   uint64
+	panics    uint64
+}
+
+// asyncEntry is either an encoded line for the output, or the fields for the sink
+type asyncEntry struct {
+	line       []byte
+	system     Fields
+	properties Fields
+}
+
+// NewAsyncWriter creates a new AsyncWriter and starts its background go routine. The optional configure func lets you set
+// the output, the size of the buffer and the overflow policy.
+func NewAsyncWriter(configure ...func(*AsyncWriterConfig)) *AsyncWriter {
+
+	conf := AsyncWriterConfig{
+		Output:     os.Stdout,
+		BufferSize: 1024,
+		Overflow:   OverflowBlock,
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	if conf.BufferSize <= 0 {
+		conf.BufferSize = 1
+	}
+
+	if conf.Encoder == nil {
+		conf.Encoder = defaultEncoder(conf.Output)
+	}
+
+	writer := newAsyncWriter(conf.BufferSize, conf.Overflow)
+	writer.output = conf.Output
+	writer.encoder = conf.Encoder
+
+	go writer.run()
+
+	return writer
+}
+
+// newAsyncWriter returns an AsyncWriter without an output, the caller sets one (or a sink) then starts run
+func newAsyncWriter(bufferSize int, overflow OverflowPolicy) *AsyncWriter {
+	return &AsyncWriter{
+		overflow: overflow,
+		entries:  make(chan asyncEntry, bufferSize),
+		flushes:  make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (writer *AsyncWriter) WriteFields(system Fields, fields ...Fields) {
+	str := serializeFields(writer.encoder, system, fields...)
+	writer.enqueue(asyncEntry{line: toLine(str)})
+}
+
+// Flush blocks until every entry queued before the call has been written, or the ctx is done.
+func (writer *AsyncWriter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	select {
+	case writer.flushes <- flushed:
+	case <-writer.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting new entries, writes everything still queued and stops the background go routine. Returns
+// early with an error if the ctx is done, and the rest of the queue is still written in the background. Entries written
+// after Close are dropped.
+func (writer *AsyncWriter) Close(ctx context.Context) error {
+	err := writer.close(ctx)
+
+	UnregisterShutdownHook(writer.shutdownName())
+	return err
+}
+
+// close stops accepting new entries and waits until everything still queued has been written, or the ctx is done
+func (writer *AsyncWriter) close(ctx context.Context) error {
+	writer.closeOnce.Do(func() {
+		// stop new entries (and wake any blocked waiting for room), wait for those already being queued, then let the
+		// background go routine write what's left. Every entry is either written or counted as dropped.
+		close(writer.stop)
+		writer.enqueuing.Lock()
+		close(writer.quit)
+		writer.enqueuing.Unlock()
+	})
+
+	select {
+	case <-writer.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RegisterShutdown registers a shutdown hook (see RegisterShutdownHook) that closes the writer, so entries still
+// queued are written when RunShutdownHooks is called or Fatal exits, as long as the output keeps up with the deadline.
+// Close unregisters it.
+func (writer *AsyncWriter) RegisterShutdown() {
+	RegisterShutdownHook(writer.shutdownName(), writer.Close)
+}
+
+func (writer *AsyncWriter) shutdownName() string {
+	return fmt.Sprintf("log.AsyncWriter(%p)", writer)
+}
+
+// Dropped returns the number of entries discarded because of the overflow policy or because the writer was closed.
+func (writer *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&writer.dropped)
+}
+
+func (writer *AsyncWriter) enqueue(entry asyncEntry) {
+	writer.enqueuing.RLock()
+	defer writer.enqueuing.RUnlock()
+
+	select {
+	case <-writer.stop:
+		writer.drop()
+		return
+	default:
+	}
+
+	switch writer.overflow {
+	case OverflowDropNewest:
+		select {
+		case writer.entries <- entry:
+		default:
+			writer.drop()
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case writer.entries <- entry:
+				return
+			default:
+			}
+
+			// make room by discarding the oldest entry, then try again
+			select {
+			case <-writer.entries:
+				writer.drop()
+			default:
+			}
+		}
+
+	default:
+		// if both are ready either is fine, as a queued entry is still written by the final drain
+		select {
+		case writer.entries <- entry:
+		case <-writer.stop:
+			writer.drop()
+		}
+	}
+}
+
+func (writer *AsyncWriter) drop() {
+	atomic.AddUint64(&writer.dropped, 1)
+}
+
+func (writer *AsyncWriter) run() {
+	defer close(writer.done)
+
+	for {
+		select {
+		case entry := <-writer.entries:
+			writer.write(entry)
+		case flushed := <-writer.flushes:
+			writer.drain()
+			close(flushed)
+		case <-writer.quit:
+			writer.drain()
+			return
+		}
+	}
+}
+
+func (writer *AsyncWriter) drain() {
+	for {
+		select {
+		case entry := <-writer.entries:
+			writer.write(entry)
+		default:
+			return
+		}
+	}
+}
+
+func (writer *AsyncWriter) write(entry asyncEntry) {
+	if writer.sink != nil {
+		writer.writeSink(entry)
+		return
+	}
+
+	// This can return an error, but we just swallow it here as what can we or a client really do? Try and log it? :)
+	writer.output.Write(entry.line)
+}
+
+func (writer *AsyncWriter) jsonEncoder() (JSONEncoder, bool) {
+	// a MultiWriter sink is given Fields, not lines
+	if writer.sink != nil {
+		return JSONEncoder{}, false
+	}
+	encoder, ok := writer.encoder.(JSONEncoder)
+	return encoder, ok
+}
+
+func (writer *AsyncWriter) writeLine(line []byte) {
+	// line belongs to the caller's pool, so queue a copy
+	entry := make([]byte, len(line))
+	copy(entry, line)
+	writer.enqueue(asyncEntry{line: entry})
+}
+
+// writeSink recovers from a panicking sink, so the background go routine keeps going
+func (writer *AsyncWriter) writeSink(entry asyncEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddUint64(&writer.panics, 1)
+			if writer.onPanic != nil {
+				writer.onPanic(r)
+			}
+		}
+	}()
+
+	writer.sink.WriteFields(entry.system, entry.properties)
+}
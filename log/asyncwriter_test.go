@@ -0,0 +1,246 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+type blockingBuffer struct {
+	mutex   sync.Mutex
+	buffer  bytes.Buffer
+	release chan struct{}
+}
+
+func (b *blockingBuffer) Write(p []byte) (int, error) {
+	if b.release != nil {
+		<-b.release
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *blockingBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func Test_AsyncWriter_Flush(t *testing.T) {
+
+	memBuffer := &blockingBuffer{}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+	defer writer.Close(context.Background())
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("info_event", Fields{"string": "hello"})
+	logger.Warn("warn_event")
+
+	err := writer.Flush(context.Background())
+	assert.Assert(t, err == nil, err)
+
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "event", "info_event")
+	assertContainsString(t, msg, "string", "hello")
+	assertContainsString(t, msg, "event", "warn_event")
+	assert.Assert(t, strings.Count(msg, "\n") == 2, msg)
+	assert.Assert(t, writer.Dropped() == 0, writer.Dropped())
+}
+
+func Test_AsyncWriter_Close_Drains(t *testing.T) {
+
+	memBuffer := &blockingBuffer{}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	for i := 0; i < 100; i++ {
+		logger.Info("info_event")
+	}
+
+	err := writer.Close(context.Background())
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, strings.Count(memBuffer.String(), "\n") == 100)
+
+	// writes after close are dropped
+	logger.Info("after_close")
+	assert.Assert(t, writer.Dropped() == 1, writer.Dropped())
+	assert.Assert(t, !strings.Contains(memBuffer.String(), "after_close"))
+}
+
+func Test_AsyncWriter_DropNewest(t *testing.T) {
+
+	memBuffer := &blockingBuffer{release: make(chan struct{})}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+		conf.BufferSize = 2
+		conf.Overflow = OverflowDropNewest
+	})
+
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	// the first entry is picked up by the background go routine and blocks on the output
+	logger.Info("first")
+	waitForEmptyQueue(t, writer)
+
+	logger.Info("second")
+	logger.Info("third")
+	logger.Info("fourth")
+	assert.Assert(t, writer.Dropped() == 1, writer.Dropped())
+
+	close(memBuffer.release)
+	writer.Close(context.Background())
+
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "event", "first")
+	assertContainsString(t, msg, "event", "second")
+	assertContainsString(t, msg, "event", "third")
+	assert.Assert(t, !strings.Contains(msg, "fourth"), msg)
+}
+
+func Test_AsyncWriter_DropOldest(t *testing.T) {
+
+	memBuffer := &blockingBuffer{release: make(chan struct{})}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+		conf.BufferSize = 2
+		conf.Overflow = OverflowDropOldest
+	})
+
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("first")
+	waitForEmptyQueue(t, writer)
+
+	logger.Info("second")
+	logger.Info("third")
+	logger.Info("fourth")
+	assert.Assert(t, writer.Dropped() == 1, writer.Dropped())
+
+	close(memBuffer.release)
+	writer.Close(context.Background())
+
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "event", "first")
+	assert.Assert(t, !strings.Contains(msg, "second"), msg)
+	assertContainsString(t, msg, "event", "third")
+	assertContainsString(t, msg, "event", "fourth")
+}
+
+func Test_AsyncWriter_Flush_Timeout(t *testing.T) {
+
+	memBuffer := &blockingBuffer{release: make(chan struct{})}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := writer.Flush(ctx)
+	assert.Assert(t, err == context.DeadlineExceeded, err)
+
+	close(memBuffer.release)
+	writer.Close(context.Background())
+}
+
+func Test_AsyncWriter_CloseWhileBlocked(t *testing.T) {
+
+	memBuffer := &blockingBuffer{}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+		conf.BufferSize = 1
+		conf.Overflow = OverflowBlock
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				logger.Info("info_event")
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond)
+	assert.NilError(t, writer.Close(context.Background()))
+	wg.Wait()
+
+	// every entry was either written before Close returned, or counted as dropped
+	written := strings.Count(memBuffer.String(), "\n")
+	assert.Assert(t, uint64(written)+writer.Dropped() == 400, "written %d dropped %d", written, writer.Dropped())
+}
+
+func waitForEmptyQueue(t *testing.T, writer *AsyncWriter) {
+	for i := 0; i < 1000; i++ {
+		if len(writer.entries) == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("background writer never picked up the queued entry")
+}
+
+func Test_AsyncWriter_RegisterShutdown(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	memBuffer := &blockingBuffer{}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+	writer.RegisterShutdown()
+	writer.RegisterShutdown() // once per writer
+	assert.Assert(t, len(shutdownHookNames()) == 1, shutdownHookNames())
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	for i := 0; i < 10; i++ {
+		logger.Info("info_event")
+	}
+
+	err := RunShutdownHooks(context.Background())
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, strings.Count(memBuffer.String(), "\n") == 10)
+
+	// closed, so the hook isn't needed any more
+	assert.Assert(t, len(shutdownHookNames()) == 0, shutdownHookNames())
+}
+
+func Test_AsyncWriter_RegisterShutdown_Deadline(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	memBuffer := &blockingBuffer{release: make(chan struct{})}
+	defer close(memBuffer.release)
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+	writer.RegisterShutdown()
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("info_event")
+
+	// the output never finishes, so the hook gives up at the deadline rather than hanging
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := RunShutdownHooks(ctx)
+	assert.Assert(t, err != nil && strings.Contains(err.Error(), context.DeadlineExceeded.Error()), err)
+	assert.Assert(t, time.Since(start) < time.Second, time.Since(start))
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// Caller is the system key holding the file, line and function that wrote the entry, when enabled
+	Caller = "caller"
+
+	// CallerEnv turns caller capture on at start up when set to "true"
+	CallerEnv = "LOG_CALLER"
+)
+
+// entrySkip is the number of logging frames above getSystemValues (or appendEntry), before the code that called
+// the Logger, eg. getSystemValues <- entry <- write <- Logger.Info <- your code
+const entrySkip = 3
+
+var callerEnabled int32
+
+func init() {
+	if enabled, err := strconv.ParseBool(os.Getenv(CallerEnv)); err == nil && enabled {
+		callerEnabled = 1
+	}
+}
+
+// SetCallerEnabled turns caller capture on or off for every Logger. When on, each entry has a "caller" with the
+// file, line and function that wrote it. It is off by default because walking the stack costs roughly a microsecond
+// per entry.
+func SetCallerEnabled(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&callerEnabled, value)
+}
+
+// CallerEnabled returns true if caller capture is on
+func CallerEnabled() bool {
+	return atomic.LoadInt32(&callerEnabled) == 1
+}
+
+// AddCallerSkip returns a copy of the Logger that reports the caller skip frames further up the stack. Only needed
+// by packages that wrap Logger (eg. monitor.Logger), so the caller is their caller rather than the wrapper itself.
+func (logger Logger) AddCallerSkip(skip int) *Logger {
+	logger.callerSkip += skip
+	return &logger
+}
+
+type callerInfo struct {
+	file     string
+	line     int
+	function string
+}
+
+// callerAt returns the frame skip levels above the function calling callerAt, so 0 is that function itself
+func callerAt(skip int) (callerInfo, bool) {
+	var pcs [1]uintptr
+	// +2 skips runtime.Callers and callerAt itself
+	if runtime.Callers(skip+2, pcs[:]) < 1 {
+		return callerInfo{}, false
+	}
+
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	if frame.PC == 0 {
+		return callerInfo{}, false
+	}
+
+	return callerInfo{
+		file:     trimPath(frame.File, 2),
+		line:     frame.Line,
+		function: trimPath(frame.Function, 1),
+	}, true
+}
+
+func (caller callerInfo) fields() Fields {
+	return Fields{
+		"file":     caller.file,
+		"line":     caller.line,
+		"function": caller.function,
+	}
+}
+
+func (caller callerInfo) appendJSON(buf []byte) []byte {
+	buf = append(buf, '{')
+	buf = appendStringField(buf, "file", caller.file, true)
+	buf = appendStringField(buf, "function", caller.function, false)
+	buf = appendKey(buf, "line", false)
+	buf = appendInt(buf, int64(caller.line))
+	return append(buf, '}')
+}
+
+// trimPath keeps the last n elements of a '/' separated path, eg. trimPath("/src/glamplify/log/logger.go", 2) => "log/logger.go"
+func trimPath(path string, n int) string {
+	end := len(path)
+	for i := 0; i < n; i++ {
+		index := strings.LastIndexByte(path[:end], '/')
+		if index < 0 {
+			return path
+		}
+		end = index
+	}
+	return path[end+1:]
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"gotest.tools/assert"
+)
+
+func Test_Caller_Disabled(t *testing.T) {
+	memBuffer, logger := newBufferLogger()
+
+	logger.Info("info_event")
+	logger.InfoWith("info_event")
+
+	assert.Assert(t, !strings.Contains(memBuffer.String(), Caller), memBuffer.String())
+}
+
+func Test_Caller_Logger(t *testing.T) {
+	defer SetCallerEnabled(false)
+	SetCallerEnabled(true)
+
+	memBuffer, logger := newBufferLogger()
+
+	line := nextLine()
+	logger.Info("info_event")
+	assertCaller(t, memBuffer.String(), line, "log.Test_Caller_Logger")
+
+	memBuffer.Reset()
+	line = nextLine()
+	logger.InfoWith("info_event", String("string", "hello"))
+	assertCaller(t, memBuffer.String(), line, "log.Test_Caller_Logger")
+
+	memBuffer.Reset()
+	line = nextLine()
+	logger.ErrorWith("error_event", errors.New("bad"))
+	assertCaller(t, memBuffer.String(), line, "log.Test_Caller_Logger")
+
+	memBuffer.Reset()
+	line = nextLine()
+	logger.Event("info_event").Fields(Fields{"string": "hello"}).Info("message")
+	assertCaller(t, memBuffer.String(), line, "log.Test_Caller_Logger")
+
+	memBuffer.Reset()
+	line = nextLine()
+	logger.Event("error_event").Error(errors.New("bad"))
+	assertCaller(t, memBuffer.String(), line, "log.Test_Caller_Logger")
+
+	memBuffer.Reset()
+	segment := logger.Event("timed_event").Start()
+	line = nextLine()
+	segment.Success("done")
+	assertCaller(t, memBuffer.String(), line, "log.Test_Caller_Logger")
+}
+
+func Test_Caller_PackageLevel(t *testing.T) {
+	defer SetCallerEnabled(false)
+	SetCallerEnabled(true)
+
+	memBuffer, logger := newBufferLogger()
+	original := defaultLogger
+	defaultLogger = logger
+	defer func() { defaultLogger = original }()
+
+	line := nextLine()
+	Warn(gcontext.RequestScopedFields{}, "warn_event")
+	assertCaller(t, memBuffer.String(), line, "log.Test_Caller_PackageLevel")
+}
+
+func Test_Caller_Wrapped(t *testing.T) {
+	defer SetCallerEnabled(false)
+	SetCallerEnabled(true)
+
+	memBuffer, logger := newBufferLogger()
+	wrapped := logger.AddCallerSkip(1)
+
+	line := nextLine()
+	logThroughWrapper(wrapped)
+	assertCaller(t, memBuffer.String(), line, "log.Test_Caller_Wrapped")
+}
+
+func Test_Caller_Streaming(t *testing.T) {
+	defer SetCallerEnabled(false)
+	SetCallerEnabled(true)
+
+	memBuffer, logger := newBufferLogger()
+	logger.InfoWith("info_event")
+
+	msg := memBuffer.String()
+	assert.Assert(t, strings.Contains(msg, `"caller":{"file":"log/caller_test.go","function":"log.Test_Caller_Streaming","line":`), msg)
+}
+
+func Test_TrimPath(t *testing.T) {
+	assert.Equal(t, trimPath("/src/glamplify/log/logger.go", 2), "log/logger.go")
+	assert.Equal(t, trimPath("logger.go", 2), "logger.go")
+	assert.Equal(t, trimPath("github.com/cultureamp/glamplify/log.Logger.Info", 1), "log.Logger.Info")
+}
+
+func logThroughWrapper(logger *Logger) {
+	logger.Info("wrapped_event")
+}
+
+// nextLine returns the line number after the one calling it
+func nextLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line + 1
+}
+
+func assertCaller(t *testing.T, log string, line int, function string) {
+	assertContainsString(t, log, "file", "log/caller_test.go")
+	assertContainsString(t, log, "function", function)
+	assert.Assert(t, strings.Contains(log, fmt.Sprintf(`"line":%d`, line)), "Expected line %d in '%s'", line, log)
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"context"
+)
+
+type loggerKey int
+
+const ctxLoggerKey loggerKey = iota
+
+// WithLogger returns a context carrying logger, so every function that receives the context can log with the same
+// fields via FromCtx. Useful for middleware that enriches the logger once per request eg.
+//
+//	ctx = log.WithLogger(ctx, log.FromCtx(ctx).With(log.Fields{"survey_id": id}))
+func WithLogger(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, ctxLoggerKey, logger)
+}
+
+// FromCtx returns the Logger added to the context by WithLogger. If there isn't one, it returns a new Logger the same
+// as NewFromCtx.
+func FromCtx(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(ctxLoggerKey).(*Logger); ok && logger != nil {
+		return logger
+	}
+	return NewFromCtx(ctx)
+}
+
+// With returns a child Logger that adds fields to every entry, on top of the fields of this Logger. The child shares
+// this Logger's writer, level (see Logger.SetLevel) and debug buffer. This Logger is not changed.
+func (logger Logger) With(fields ...Fields) *Logger {
+	logger.fields = logger.fields.Merge(fields...)
+	return &logger
+}
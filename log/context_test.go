@@ -0,0 +1,65 @@
+package log_test
+
+import (
+	"context"
+	"testing"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/log"
+	"github.com/cultureamp/glamplify/log/logtest"
+	"gotest.tools/assert"
+)
+
+func Test_With(t *testing.T) {
+	logger, recorder := logtest.NewLogger(gcontext.RequestScopedFields{TraceID: "1-2-3"}, log.Fields{"app_area": "surveys"})
+
+	child := logger.With(log.Fields{"survey_id": "s-1"})
+	grandChild := child.With(log.Fields{"question_id": "q-1", "survey_id": "s-2"})
+
+	child.Info("child_event")
+	grandChild.Info("grand_child_event", log.Fields{"count": 1})
+	logger.Info("parent_event")
+
+	entry := recorder.AssertLogged(t, "child_event")
+	logtest.AssertField(t, entry, "trace_id", "1-2-3")
+	logtest.AssertField(t, entry, "properties.app_area", "surveys")
+	logtest.AssertField(t, entry, "properties.survey_id", "s-1")
+	logtest.AssertNoField(t, entry, "properties.question_id")
+
+	entry = recorder.AssertLogged(t, "grand_child_event")
+	logtest.AssertField(t, entry, "properties.app_area", "surveys")
+	logtest.AssertField(t, entry, "properties.survey_id", "s-2")
+	logtest.AssertField(t, entry, "properties.question_id", "q-1")
+	logtest.AssertField(t, entry, "properties.count", 1)
+
+	// the parent is not changed
+	entry = recorder.AssertLogged(t, "parent_event")
+	logtest.AssertNoField(t, entry, "properties.survey_id")
+}
+
+func Test_FromCtx(t *testing.T) {
+	logger, recorder := logtest.NewLogger(gcontext.RequestScopedFields{TraceID: "1-2-3"})
+
+	ctx := log.WithLogger(context.Background(), logger.With(log.Fields{"survey_id": "s-1"}))
+	ctx = enrich(ctx)
+	log.FromCtx(ctx).Info("deep_event")
+
+	entry := recorder.AssertLogged(t, "deep_event")
+	logtest.AssertField(t, entry, "trace_id", "1-2-3")
+	logtest.AssertField(t, entry, "properties.survey_id", "s-1")
+	logtest.AssertField(t, entry, "properties.question_id", "q-1")
+}
+
+func Test_FromCtx_NoLogger(t *testing.T) {
+	ctx := gcontext.AddRequestFields(context.Background(), gcontext.RequestScopedFields{TraceID: "4-5-6"})
+
+	logger := log.FromCtx(ctx)
+	assert.Assert(t, logger != nil)
+
+	logger = log.FromCtx(log.WithLogger(ctx, nil))
+	assert.Assert(t, logger != nil)
+}
+
+func enrich(ctx context.Context) context.Context {
+	return log.WithLogger(ctx, log.FromCtx(ctx).With(log.Fields{"question_id": "q-1"}))
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// Buffered is added to the properties of entries that were held back by a debug buffer and written because of an error
+	Buffered = "buffered"
+)
+
+type bufferKey int
+
+const debugBufferKey bufferKey = iota
+
+// DebugBufferConfig for setting initial values for a debug buffer
+type DebugBufferConfig struct {
+	// Size is the maximum number of entries held. Once full the oldest entries are discarded.
+	Size int
+}
+
+// debugBuffer is a ring buffer of entries that were below the log level. It is shared by every Logger created from
+// the same context, so an Error on any of them writes the entries held for the whole request.
+type debugBuffer struct {
+	mutex   sync.Mutex
+	entries []bufferedEntry
+	start   int
+	count   int
+}
+
+// bufferedEntry keeps the Logger that held it, so it is written through that Logger's hooks and writer
+type bufferedEntry struct {
+	logger     Logger
+	sev        string
+	event      string
+	system     Fields
+	properties Fields
+}
+
+// AddDebugBuffer returns a context that makes every Logger created from it (via NewFromCtx or NewFromRequest) hold
+// entries that are below the log level, instead of discarding them. If an ERROR or FATAL is then logged, the held
+// entries are written first (in order, with "buffered": true), otherwise they are discarded with the context.
+// Useful as middleware so that a failing request keeps its DEBUG lines while a successful one doesn't.
+func AddDebugBuffer(ctx context.Context, configure ...func(*DebugBufferConfig)) context.Context {
+	conf := DebugBufferConfig{
+		Size: 100,
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	if conf.Size <= 0 {
+		conf.Size = 1
+	}
+
+	buffer := &debugBuffer{
+		entries: make([]bufferedEntry, conf.Size),
+	}
+	return context.WithValue(ctx, debugBufferKey, buffer)
+}
+
+func debugBufferFromCtx(ctx context.Context) *debugBuffer {
+	buffer, _ := ctx.Value(debugBufferKey).(*debugBuffer)
+	return buffer
+}
+
+func (buffer *debugBuffer) add(logger Logger, sev string, event string, system Fields, properties Fields) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+
+	size := len(buffer.entries)
+	index := (buffer.start + buffer.count) % size
+	buffer.entries[index] = bufferedEntry{logger: logger, sev: sev, event: event, system: system, properties: properties}
+
+	if buffer.count < size {
+		buffer.count++
+	} else {
+		// full, so we just overwrote the oldest
+		buffer.start = (buffer.start + 1) % size
+	}
+}
+
+// flush writes the held entries oldest first, and empties the buffer
+func (buffer *debugBuffer) flush() {
+	buffer.mutex.Lock()
+	held := make([]bufferedEntry, 0, buffer.count)
+	for i := 0; i < buffer.count; i++ {
+		index := (buffer.start + i) % len(buffer.entries)
+		held = append(held, buffer.entries[index])
+		buffer.entries[index] = bufferedEntry{}
+	}
+	buffer.start = 0
+	buffer.count = 0
+	buffer.mutex.Unlock()
+
+	for _, entry := range held {
+		entry.properties[Buffered] = true
+		entry.logger.writeEntry(entry.sev, entry.event, entry.system, entry.properties)
+	}
+}
@@ -0,0 +1,183 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_DebugBuffer_FlushOnError(t *testing.T) {
+	defer SetLevel(DebugSev)
+	SetLevel(InfoSev)
+	defer SetRedactor(nil)
+	SetRedactor(NewRedactor())
+
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+
+	reqCtx := AddDebugBuffer(ctx)
+	logger := NewFromCtxWithCustomerWriter(reqCtx, writer)
+	other := NewFromCtxWithCustomerWriter(reqCtx, writer)
+
+	logger.Debug("first_debug", Fields{"password": "abc"})
+	other.Debug("second_debug")
+	logger.Info("info_event")
+
+	msg := memBuffer.String()
+	assert.Assert(t, !strings.Contains(msg, "first_debug"), msg)
+	assert.Assert(t, !strings.Contains(msg, "second_debug"), msg)
+	assertContainsString(t, msg, "event", "info_event")
+
+	memBuffer.Reset()
+	other.Error("request_failed", errors.New("bad"))
+
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Assert(t, len(lines) == 3, lines)
+	assertContainsString(t, lines[0], "event", "first_debug")
+	assertContainsString(t, lines[0], "password", "[REDACTED]")
+	assertContainsString(t, lines[0], "trace_id", "1-2-3")
+	assert.Assert(t, strings.Contains(lines[0], `"buffered":true`), lines[0])
+	assertContainsString(t, lines[1], "event", "second_debug")
+	assertContainsString(t, lines[2], "event", "request_failed")
+	assert.Assert(t, !strings.Contains(lines[2], `"buffered"`), lines[2])
+
+	// the buffer is emptied once written
+	memBuffer.Reset()
+	logger.Error("request_failed_again", errors.New("bad"))
+	lines = strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Assert(t, len(lines) == 1, lines)
+}
+
+func Test_DebugBuffer_DiscardedWithoutError(t *testing.T) {
+	defer SetLevel(DebugSev)
+	SetLevel(InfoSev)
+
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+
+	logger := NewFromCtxWithCustomerWriter(AddDebugBuffer(ctx), writer)
+	logger.Debug("debug_event")
+	logger.Warn("warn_event")
+
+	msg := memBuffer.String()
+	assert.Assert(t, !strings.Contains(msg, "debug_event"), msg)
+	assertContainsString(t, msg, "event", "warn_event")
+
+	// another request never sees them
+	NewFromCtxWithCustomerWriter(AddDebugBuffer(ctx), writer).Error("other_failed", errors.New("bad"))
+	assert.Assert(t, !strings.Contains(memBuffer.String(), "debug_event"), memBuffer.String())
+}
+
+func Test_DebugBuffer_Bounded(t *testing.T) {
+	defer SetLevel(DebugSev)
+	SetLevel(InfoSev)
+
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+
+	logger := NewFromCtxWithCustomerWriter(AddDebugBuffer(ctx, func(conf *DebugBufferConfig) {
+		conf.Size = 3
+	}), writer)
+
+	for _, event := range []string{"debug_1", "debug_2", "debug_3", "debug_4", "debug_5"} {
+		logger.Debug(event)
+	}
+	logger.Error("request_failed", errors.New("bad"))
+
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Assert(t, len(lines) == 4, lines)
+	assertContainsString(t, lines[0], "event", "debug_3")
+	assertContainsString(t, lines[1], "event", "debug_4")
+	assertContainsString(t, lines[2], "event", "debug_5")
+	assertContainsString(t, lines[3], "event", "request_failed")
+}
+
+func Test_DebugBuffer_NotUsedWithoutCtx(t *testing.T) {
+	defer SetLevel(DebugSev)
+	SetLevel(InfoSev)
+
+	memBuffer, logger := newBufferLogger()
+	logger.Debug("debug_event")
+	logger.Error("request_failed", errors.New("bad"))
+
+	assert.Assert(t, !strings.Contains(memBuffer.String(), "debug_event"), memBuffer.String())
+}
+
+func Test_DebugBuffer_FlushToHoldingWriter(t *testing.T) {
+	defer SetLevel(DebugSev)
+	SetLevel(InfoSev)
+
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+	otherBuffer := &bytes.Buffer{}
+	otherWriter := NewWriter(func(conf *WriterConfig) {
+		conf.Output = otherBuffer
+	})
+
+	reqCtx := AddDebugBuffer(ctx)
+	logger := NewFromCtxWithCustomerWriter(reqCtx, writer)
+	other := NewFromCtxWithCustomerWriter(reqCtx, otherWriter)
+
+	logger.Debug("first_debug")
+	other.Debug("second_debug")
+	logger.Error("request_failed", errors.New("bad"))
+
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Assert(t, len(lines) == 2, lines)
+	assertContainsString(t, lines[0], "event", "first_debug")
+	assertContainsString(t, lines[1], "event", "request_failed")
+
+	msg := otherBuffer.String()
+	assertContainsString(t, msg, "event", "second_debug")
+	assert.Assert(t, strings.Contains(msg, `"buffered":true`), msg)
+}
+
+func Test_DebugBuffer_FlushThroughHooks(t *testing.T) {
+	defer SetLevel(DebugSev)
+	SetLevel(InfoSev)
+
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+
+	var fired []string
+	hook := HookFunc(func(severity string, event string, system Fields, properties Fields) bool {
+		fired = append(fired, severity+":"+event)
+		if event == "secret_debug" {
+			return false
+		}
+		properties["hooked"] = properties[Buffered] == true
+		return true
+	})
+
+	reqCtx := AddDebugBuffer(ctx)
+	logger := NewFromCtxWithCustomerWriter(reqCtx, writer).WithHooks(hook)
+	plain := NewFromCtxWithCustomerWriter(reqCtx, writer)
+
+	logger.Debug("first_debug")
+	logger.Debug("secret_debug")
+	plain.Debug("plain_debug")
+	plain.Error("request_failed", errors.New("bad"))
+
+	assert.DeepEqual(t, fired, []string{DebugSev + ":first_debug", DebugSev + ":secret_debug"})
+
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Assert(t, len(lines) == 3, lines)
+	assertContainsString(t, lines[0], "event", "first_debug")
+	assert.Assert(t, strings.Contains(lines[0], `"hooked":true`), lines[0])
+	assertContainsString(t, lines[1], "event", "plain_debug")
+	assert.Assert(t, !strings.Contains(lines[1], `"hooked"`), lines[1])
+	assertContainsString(t, lines[2], "event", "request_failed")
+}
@@ -0,0 +1,272 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// JSONFormat writes one JSON object per line (default)
+	JSONFormat = "json"
+	// LogfmtFormat writes one line of key=value pairs per entry
+	LogfmtFormat = "logfmt"
+	// ConsoleFormat writes a coloured, human readable line per entry. Useful for local development.
+	ConsoleFormat = "console"
+
+	// LogFormatEnv selects the Encoder when WriterConfig.Encoder is not set
+	LogFormatEnv = "LOG_FORMAT"
+)
+
+// Encoder turns an entry into a single line of text. The fields are the system values with the properties
+// nested under "properties", and all keys already in snake_case.
+type Encoder interface {
+	Encode(fields Fields) string
+}
+
+// JSONEncoder is the standard Culture Amp log format. The system values are written first in a fixed order, then the
+// exception and properties. Values and lines over the limits are truncated, and listed under "_truncated".
+type JSONEncoder struct {
+	// MaxValueLength truncates longer strings. 0 uses DefaultMaxValueLength, and -1 turns it off.
+	MaxValueLength int
+	// MaxLineLength leaves out the biggest properties until the entry fits. 0 uses DefaultMaxLineLength, and -1 turns it off.
+	MaxLineLength int
+}
+
+// LogfmtEncoder writes key=value pairs, with nested keys joined by a '.' eg. properties.count=1
+type LogfmtEncoder struct{}
+
+// ConsoleEncoder writes time, severity, event and message first, then the sorted properties, then any other
+// non-empty system values. Exceptions are written on the following lines.
+type ConsoleEncoder struct {
+	Colour bool
+}
+
+// NewEncoder returns the Encoder for a format name (see JSONFormat, LogfmtFormat and ConsoleFormat)
+func NewEncoder(format string, colour bool) (Encoder, error) {
+	switch strings.ToLower(format) {
+	case JSONFormat:
+		return JSONEncoder{}, nil
+	case LogfmtFormat:
+		return LogfmtEncoder{}, nil
+	case ConsoleFormat:
+		return ConsoleEncoder{Colour: colour}, nil
+	}
+	return nil, fmt.Errorf("unknown log format '%s', must be one of %s, %s or %s", format, JSONFormat, LogfmtFormat, ConsoleFormat)
+}
+
+// defaultEncoder uses LOG_FORMAT if set, otherwise the console format when writing to a terminal, otherwise JSON
+func defaultEncoder(output io.Writer) Encoder {
+	tty := isTerminal(output)
+
+	if format, ok := os.LookupEnv(LogFormatEnv); ok {
+		if encoder, err := NewEncoder(format, tty); err == nil {
+			return encoder
+		}
+	}
+
+	if tty {
+		return ConsoleEncoder{Colour: true}
+	}
+	return JSONEncoder{}
+}
+
+func isTerminal(output io.Writer) bool {
+	file, ok := output.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (encoder JSONEncoder) Encode(fields Fields) string {
+	return encodeJSON(fields, encoder.maxValue(), encoder.maxLine())
+}
+
+func (encoder LogfmtEncoder) Encode(fields Fields) string {
+	flat := map[string]interface{}{}
+	flatten("", fields, flat)
+
+	var sb strings.Builder
+	for _, key := range orderedKeys(flat, []string{Time, Severity, Event}) {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		sb.WriteString(logfmtValue(flat[key]))
+	}
+	return sb.String()
+}
+
+const (
+	ansiReset  = "\033[0m"
+	ansiDim    = "\033[2m"
+	ansiBold   = "\033[1m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiBlue   = "\033[34m"
+	ansiGrey   = "\033[37m"
+	ansiPurple = "\033[35m"
+)
+
+var severityColours = map[string]string{
+	DebugSev: ansiGrey,
+	InfoSev:  ansiBlue,
+	WarnSev:  ansiYellow,
+	ErrorSev: ansiRed,
+	FatalSev: ansiPurple,
+}
+
+func (encoder ConsoleEncoder) Encode(fields Fields) string {
+	var sb strings.Builder
+
+	severity := fmt.Sprint(fields[Severity])
+	sb.WriteString(encoder.colour(ansiDim, fmt.Sprint(fields[Time])))
+	sb.WriteByte(' ')
+	sb.WriteString(encoder.colour(severityColours[severity], fmt.Sprintf("%-5s", severity)))
+	sb.WriteByte(' ')
+	sb.WriteString(encoder.colour(ansiBold, fmt.Sprint(fields[Event])))
+
+	properties := map[string]interface{}{}
+	if props, ok := fields[Properties].(Fields); ok {
+		flatten("", props, properties)
+	}
+	if message, ok := properties[Message]; ok {
+		sb.WriteByte(' ')
+		sb.WriteString(fmt.Sprint(message))
+		delete(properties, Message)
+	}
+
+	for _, key := range orderedKeys(properties, nil) {
+		sb.WriteByte(' ')
+		sb.WriteString(encoder.colour(ansiBlue, key+"="))
+		sb.WriteString(logfmtValue(properties[key]))
+	}
+
+	others := map[string]interface{}{}
+	for key, value := range fields {
+		switch key {
+		case Time, Severity, Event, Properties, Exception:
+			continue
+		}
+		if value == nil || value == "" {
+			continue
+		}
+		others[key] = value
+	}
+	for _, key := range orderedKeys(others, nil) {
+		sb.WriteByte(' ')
+		sb.WriteString(encoder.colour(ansiDim, key+"="+logfmtValue(others[key])))
+	}
+
+	if exception, ok := fields[Exception].(Fields); ok {
+		if err, ok := exception["error"]; ok {
+			sb.WriteString("\n    ")
+			sb.WriteString(encoder.colour(ansiRed, fmt.Sprint(err)))
+		}
+		if causes, ok := exception["causes"].([]Fields); ok {
+			// the first is the error itself
+			for i := 1; i < len(causes); i++ {
+				sb.WriteString("\n    ")
+				sb.WriteString(encoder.colour(ansiRed, fmt.Sprintf("caused by %v: %v", causes[i]["type"], causes[i]["message"])))
+			}
+		}
+		if trace, ok := exception["trace"].(string); ok && trace != "" {
+			for _, line := range strings.Split(strings.TrimRight(trace, "\n"), "\n") {
+				sb.WriteString("\n    ")
+				sb.WriteString(encoder.colour(ansiDim, line))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func (encoder ConsoleEncoder) colour(colour string, text string) string {
+	if !encoder.Colour || colour == "" {
+		return text
+	}
+	return colour + text + ansiReset
+}
+
+// flatten nested Fields (and maps) into dotted keys eg. {"a": {"b": 1}} => {"a.b": 1}
+func flatten(prefix string, value interface{}, flat map[string]interface{}) {
+	var nested map[string]interface{}
+	switch v := value.(type) {
+	case Fields:
+		nested = v
+	case map[string]interface{}:
+		nested = v
+	}
+
+	if nested == nil {
+		flat[prefix] = value
+		return
+	}
+
+	for k, v := range nested {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		flatten(key, v, flat)
+	}
+}
+
+// orderedKeys returns the first keys (if present) in the order given, followed by the rest sorted
+func orderedKeys(flat map[string]interface{}, first []string) []string {
+	keys := make([]string, 0, len(flat))
+	seen := map[string]bool{}
+	for _, key := range first {
+		if _, ok := flat[key]; ok {
+			keys = append(keys, key)
+			seen[key] = true
+		}
+	}
+
+	rest := make([]string, 0, len(flat))
+	for key := range flat {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(keys, rest...)
+}
+
+func logfmtValue(value interface{}) string {
+	var str string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		str = v
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case error:
+		str = v.Error()
+	default:
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			str = fmt.Sprint(v)
+		} else {
+			str = string(bytes)
+		}
+	}
+
+	if str == "" || strings.ContainsAny(str, " =\"\t\r\n\\") {
+		return strconv.Quote(str)
+	}
+	return str
+}
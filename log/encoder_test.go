@@ -0,0 +1,104 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_Encoder_Logfmt(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Encoder = LogfmtEncoder{}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("info_event", Fields{
+		"string": "hello world",
+		"int":    123,
+		"nested": Fields{"flag": true},
+	})
+
+	msg := memBuffer.String()
+	assert.Assert(t, strings.HasPrefix(msg, "time="), msg)
+	assert.Assert(t, strings.Contains(msg, " severity=INFO event=info_event "), msg)
+	assert.Assert(t, strings.Contains(msg, ` properties.string="hello world"`), msg)
+	assert.Assert(t, strings.Contains(msg, " properties.int=123"), msg)
+	assert.Assert(t, strings.Contains(msg, " properties.nested.flag=true"), msg)
+	assert.Assert(t, strings.Contains(msg, " trace_id=1-2-3"), msg)
+	assert.Assert(t, strings.Contains(msg, ` aws_region=us-west-02`), msg)
+	assert.Assert(t, strings.Count(msg, "\n") == 1, msg)
+}
+
+func Test_Encoder_Console(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Encoder = ConsoleEncoder{}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Event("info_event").Fields(Fields{"zebra": 1, "apple": "two words"}).Info("the message")
+
+	msg := memBuffer.String()
+	assert.Assert(t, strings.Contains(msg, " INFO  info_event the message apple=\"two words\" zebra=1 "), msg)
+	assert.Assert(t, strings.Index(msg, "zebra=1") < strings.Index(msg, "trace_id=1-2-3"), msg)
+	assert.Assert(t, !strings.Contains(msg, "\033["), msg)
+
+	memBuffer.Reset()
+	logger.Error("error_event", errors.New("it broke"))
+
+	msg = memBuffer.String()
+	assert.Assert(t, strings.Contains(msg, " ERROR error_event"), msg)
+	assert.Assert(t, strings.Contains(msg, "\n    it broke"), msg)
+
+	memBuffer.Reset()
+	logger.Error("error_event", fmt.Errorf("wrapped: %w", errors.New("it broke")))
+
+	msg = memBuffer.String()
+	assert.Assert(t, strings.Contains(msg, "\n    wrapped: it broke\n    caused by *errors.errorString: it broke"), msg)
+}
+
+func Test_Encoder_Console_Colour(t *testing.T) {
+	encoder := ConsoleEncoder{Colour: true}
+	str := encoder.Encode(Fields{
+		Time:     "2020-01-01T00:00:00.000Z",
+		Severity: WarnSev,
+		Event:    "warn_event",
+	})
+
+	assert.Assert(t, strings.Contains(str, ansiYellow+"WARN "+ansiReset), str)
+}
+
+func Test_Encoder_Env(t *testing.T) {
+	defer os.Unsetenv(LogFormatEnv)
+
+	os.Setenv(LogFormatEnv, "logfmt")
+	_, ok := defaultEncoder(&bytes.Buffer{}).(LogfmtEncoder)
+	assert.Assert(t, ok)
+
+	os.Setenv(LogFormatEnv, "CONSOLE")
+	encoder, ok := defaultEncoder(&bytes.Buffer{}).(ConsoleEncoder)
+	assert.Assert(t, ok)
+	assert.Assert(t, !encoder.Colour)
+
+	os.Setenv(LogFormatEnv, "unknown")
+	_, ok = defaultEncoder(&bytes.Buffer{}).(JSONEncoder)
+	assert.Assert(t, ok)
+
+	os.Unsetenv(LogFormatEnv)
+	_, ok = defaultEncoder(&bytes.Buffer{}).(JSONEncoder)
+	assert.Assert(t, ok)
+}
+
+func Test_NewEncoder_Unknown(t *testing.T) {
+	encoder, err := NewEncoder("xml", false)
+	assert.Assert(t, encoder == nil, encoder)
+	assert.Assert(t, err != nil, err)
+}
@@ -0,0 +1,194 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// GCStatsEnv turns the gc_stats block on errors on at start up when set to "true"
+	GCStatsEnv = "LOG_GC_STATS"
+
+	// maxErrorChain stops a badly behaved Unwrap (eg. one that returns itself) looping forever, and limits how many
+	// errors of a big tree (eg. errors.Join) are described
+	maxErrorChain = 32
+)
+
+var gcStatsEnabled int32
+
+func init() {
+	if enabled, err := strconv.ParseBool(os.Getenv(GCStatsEnv)); err == nil && enabled {
+		gcStatsEnabled = 1
+	}
+}
+
+// SetGCStatsEnabled turns the "gc_stats" block in the exception of ERROR and FATAL entries on or off. It is off by default.
+func SetGCStatsEnabled(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&gcStatsEnabled, value)
+}
+
+// GCStatsEnabled returns true if errors include GC stats
+func GCStatsEnabled() bool {
+	return atomic.LoadInt32(&gcStatsEnabled) == 1
+}
+
+// fieldsError lets an error carry structured fields, see AttachFields
+type fieldsError struct {
+	err    error
+	fields Fields
+}
+
+// AttachFields returns an error that wraps err with structured fields. When logged, the fields of every error in the
+// chain are added to the exception (the outermost wins if a key is used twice). errors.Is, errors.As and
+// errors.Unwrap all see err.
+func AttachFields(err error, fields Fields) error {
+	if err == nil {
+		return nil
+	}
+	return &fieldsError{err: err, fields: fields}
+}
+
+func (e *fieldsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldsError) Unwrap() error {
+	return e.err
+}
+
+// Fields returns the fields attached to the error
+func (e *fieldsError) Fields() Fields {
+	return e.fields
+}
+
+// unwrapErrors returns the errors err wraps using Unwrap (go 1.13), Unwrap returning a list (go 1.20, eg. errors.Join)
+// or Cause (pkg/errors)
+func unwrapErrors(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if next := e.Unwrap(); next != nil {
+			return []error{next}
+		}
+	case interface{ Unwrap() []error }:
+		return e.Unwrap()
+	case interface{ Cause() error }:
+		if next := e.Cause(); next != nil {
+			return []error{next}
+		}
+	}
+	return nil
+}
+
+// errorFields returns the fields attached to an error, either with AttachFields or by implementing
+// Fields() log.Fields or Fields() map[string]interface{}
+func errorFields(err error) Fields {
+	switch e := err.(type) {
+	case interface{ Fields() Fields }:
+		return e.Fields()
+	case interface{ Fields() map[string]interface{} }:
+		return e.Fields()
+	}
+	return nil
+}
+
+// errorStackTrace returns the stack captured when the error was created, for errors with a pkg/errors style
+// StackTrace() method. Any slice of program counters is accepted, so we don't depend on pkg/errors.
+func errorStackTrace(err error) (string, bool) {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return "", false
+	}
+
+	trace := method.Call(nil)[0]
+	if trace.Kind() != reflect.Slice || trace.Type().Elem().Kind() != reflect.Uintptr || trace.Len() == 0 {
+		return "", false
+	}
+
+	pcs := make([]uintptr, trace.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(trace.Index(i).Uint())
+	}
+	return formatStack(pcs), true
+}
+
+// formatStack writes program counters in the same layout as debug.Stack (without the goroutine header)
+func formatStack(pcs []uintptr) string {
+	var sb strings.Builder
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			sb.WriteString(frame.Function)
+			sb.WriteString("()\n\t")
+			sb.WriteString(frame.File)
+			sb.WriteByte(':')
+			sb.WriteString(strconv.Itoa(frame.Line))
+			sb.WriteByte('\n')
+		}
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// errorChain describes err and every error it wraps. Errors that wrap more than one (eg. errors.Join) are walked depth
+// first, so each branch is listed in turn.
+type errorChain struct {
+	causes []Fields
+	fields Fields
+	trace  string
+}
+
+func newErrorChain(err error) errorChain {
+	chain := errorChain{fields: Fields{}}
+
+	message := ""
+	pending := []error{err}
+	for visited := 0; len(pending) > 0 && visited < maxErrorChain; visited++ {
+		err := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if err == nil {
+			continue
+		}
+
+		// wrappers that only add a stack or fields repeat the message of the error they wrap, so skip them
+		if current := strings.TrimSpace(err.Error()); current != message {
+			chain.causes = append(chain.causes, Fields{
+				"type":    fmt.Sprintf("%T", err),
+				"message": current,
+			})
+			message = current
+		}
+
+		// the innermost stack trace is the closest to where it all went wrong (the last branch's, if there are several)
+		if trace, ok := errorStackTrace(err); ok {
+			chain.trace = trace
+		}
+
+		for k, v := range errorFields(err) {
+			// the outermost wins
+			if _, ok := chain.fields[k]; !ok {
+				chain.fields[k] = v
+			}
+		}
+
+		// push the branches in reverse, so the first is described next
+		next := unwrapErrors(err)
+		for i := len(next) - 1; i >= 0; i-- {
+			pending = append(pending, next[i])
+		}
+	}
+
+	return chain
+}
@@ -0,0 +1,182 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FatalPolicy controls what Fatal does after writing the entry
+type FatalPolicy int
+
+const (
+	// FatalPanic calls panic(event) (default). Shutdown hooks are not run, as the panic may be recovered.
+	FatalPanic FatalPolicy = iota
+	// FatalExit runs the shutdown hooks and then calls os.Exit(ExitCode)
+	FatalExit
+	// FatalHook calls FatalConfig.Hook. If the hook returns, Fatal panics as it must not return to the caller.
+	FatalHook
+)
+
+// FatalConfig for setting what Fatal does
+type FatalConfig struct {
+	Policy FatalPolicy
+	// ExitCode is used by FatalExit
+	ExitCode int
+	// ShutdownTimeout is how long FatalExit waits for the shutdown hooks
+	ShutdownTimeout time.Duration
+	// Hook is called by FatalHook with the (snake_case) event and error
+	Hook func(event string, err error)
+}
+
+// ShutdownHook flushes or closes something before the process exits, eg. AsyncWriter.Flush or monitor.FlushLogs
+type ShutdownHook func(ctx context.Context) error
+
+type shutdownHook struct {
+	name string
+	hook ShutdownHook
+}
+
+var (
+	fatalMutex sync.RWMutex
+	fatalConf  = defaultFatalConfig()
+
+	shutdownMutex sync.Mutex
+	shutdownHooks []shutdownHook
+
+	// so tests can check FatalExit without exiting
+	osExit = os.Exit
+)
+
+func defaultFatalConfig() FatalConfig {
+	return FatalConfig{
+		Policy:          FatalPanic,
+		ExitCode:        1,
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+// SetFatalPolicy changes what Fatal does after writing the entry, eg.
+//
+//	log.SetFatalPolicy(func(conf *log.FatalConfig) { conf.Policy = log.FatalExit })
+func SetFatalPolicy(configure ...func(*FatalConfig)) {
+	conf := defaultFatalConfig()
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	fatalMutex.Lock()
+	defer fatalMutex.Unlock()
+	fatalConf = conf
+}
+
+// GetFatalPolicy returns the current fatal settings
+func GetFatalPolicy() FatalConfig {
+	fatalMutex.RLock()
+	defer fatalMutex.RUnlock()
+	return fatalConf
+}
+
+// RegisterShutdownHook adds a hook run by RunShutdownHooks (and by Fatal with FatalExit). Hooks run in the reverse
+// order they were registered, like defer. Registering a name again replaces the earlier hook.
+func RegisterShutdownHook(name string, hook ShutdownHook) {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	for i, h := range shutdownHooks {
+		if h.name == name {
+			shutdownHooks[i].hook = hook
+			return
+		}
+	}
+	shutdownHooks = append(shutdownHooks, shutdownHook{name: name, hook: hook})
+}
+
+// UnregisterShutdownHook removes the hook registered with name, if there is one
+func UnregisterShutdownHook(name string) {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	for i, h := range shutdownHooks {
+		if h.name == name {
+			shutdownHooks = append(shutdownHooks[:i], shutdownHooks[i+1:]...)
+			return
+		}
+	}
+}
+
+// RunShutdownHooks runs every registered hook, newest first, even if earlier ones fail or the ctx is done. It returns
+// an error naming every hook that failed. Call it before your process exits normally too.
+func RunShutdownHooks(ctx context.Context) error {
+	shutdownMutex.Lock()
+	registered := make([]shutdownHook, len(shutdownHooks))
+	copy(registered, shutdownHooks)
+	shutdownMutex.Unlock()
+
+	var failures []string
+	for i := len(registered) - 1; i >= 0; i-- {
+		if err := runShutdownHook(ctx, registered[i]); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", registered[i].name, err.Error()))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("shutdown hooks failed: %s", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+func runShutdownHook(ctx context.Context, hook shutdownHook) (err error) {
+	defer func() {
+		// a broken hook mustn't stop the others
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return hook.hook(ctx)
+}
+
+// ShutdownFunc adapts a shutdown func without a context or error, eg. notify.Notifier.Shutdown, so it can be
+// registered. The hook returns ctx.Err() if f is still running when ctx is done.
+//
+//	log.RegisterShutdownHook("bugsnag", log.ShutdownFunc(notifier.Shutdown))
+func ShutdownFunc(f func()) ShutdownHook {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f()
+		}()
+
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// fatal is called after a FATAL entry is written and never returns
+func fatal(event string, err error) {
+	conf := GetFatalPolicy()
+
+	switch conf.Policy {
+	case FatalExit:
+		ctx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+		RunShutdownHooks(ctx)
+		cancel()
+		osExit(conf.ExitCode)
+
+	case FatalHook:
+		if conf.Hook != nil {
+			conf.Hook(event, err)
+		}
+	}
+
+	// time to panic!
+	panic(event)
+}
@@ -0,0 +1,212 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func resetFatal() {
+	SetFatalPolicy()
+	osExit = os.Exit
+
+	shutdownMutex.Lock()
+	shutdownHooks = nil
+	shutdownMutex.Unlock()
+}
+
+func shutdownHookNames() []string {
+	shutdownMutex.Lock()
+	defer shutdownMutex.Unlock()
+
+	names := []string{}
+	for _, h := range shutdownHooks {
+		names = append(names, h.name)
+	}
+	return names
+}
+
+// exitCode is what the fake os.Exit panics with, so the test keeps running
+type exitCode int
+
+func fakeExit() {
+	osExit = func(code int) { panic(exitCode(code)) }
+}
+
+func Test_Fatal_DefaultPolicy_Panics(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	ran := false
+	RegisterShutdownHook("flag", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	memBuffer, logger := newBufferLogger()
+
+	defer func() {
+		r := recover()
+		assert.Equal(t, r, "fatal_event")
+		assert.Assert(t, !ran, "shutdown hooks shouldn't run when panicking")
+		assertContainsString(t, memBuffer.String(), "severity", "FATAL")
+	}()
+
+	logger.Fatal("fatal event", errors.New("fatal"))
+}
+
+func Test_Fatal_ExitPolicy(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	fakeExit()
+	SetFatalPolicy(func(conf *FatalConfig) {
+		conf.Policy = FatalExit
+		conf.ExitCode = 3
+	})
+
+	var order []string
+	RegisterShutdownHook("first", func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	RegisterShutdownHook("second", func(ctx context.Context) error {
+		order = append(order, "second")
+		return errors.New("failed")
+	})
+
+	memBuffer, logger := newBufferLogger()
+
+	defer func() {
+		r := recover()
+		assert.Equal(t, r, exitCode(3))
+		assert.DeepEqual(t, order, []string{"second", "first"})
+		assertContainsString(t, memBuffer.String(), "event", "fatal_event")
+	}()
+
+	logger.FatalWith("fatal event", errors.New("fatal"))
+}
+
+func Test_Fatal_ExitPolicy_Timeout(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	fakeExit()
+	SetFatalPolicy(func(conf *FatalConfig) {
+		conf.Policy = FatalExit
+		conf.ShutdownTimeout = 10 * time.Millisecond
+	})
+
+	block := make(chan struct{})
+	defer close(block)
+	RegisterShutdownHook("stuck", ShutdownFunc(func() { <-block }))
+
+	_, logger := newBufferLogger()
+
+	start := time.Now()
+	defer func() {
+		r := recover()
+		assert.Equal(t, r, exitCode(1))
+		assert.Assert(t, time.Since(start) < time.Second)
+	}()
+
+	logger.Fatal("fatal event", errors.New("fatal"))
+}
+
+func Test_Fatal_HookPolicy(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	var gotEvent string
+	var gotErr error
+	SetFatalPolicy(func(conf *FatalConfig) {
+		conf.Policy = FatalHook
+		conf.Hook = func(event string, err error) {
+			gotEvent = event
+			gotErr = err
+		}
+	})
+
+	_, logger := newBufferLogger()
+
+	defer func() {
+		// the hook returned, so we still panic
+		r := recover()
+		assert.Equal(t, r, "fatal_event")
+		assert.Equal(t, gotEvent, "fatal_event")
+		assert.Error(t, gotErr, "fatal")
+	}()
+
+	logger.Fatal("fatal event", errors.New("fatal"))
+}
+
+func Test_ShutdownHooks(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	var order []string
+	hook := func(name string) ShutdownHook {
+		return func(ctx context.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	RegisterShutdownHook("a", hook("a"))
+	RegisterShutdownHook("b", hook("b"))
+	RegisterShutdownHook("c", hook("c"))
+	RegisterShutdownHook("a", hook("a2"))
+	UnregisterShutdownHook("b")
+	UnregisterShutdownHook("missing")
+
+	err := RunShutdownHooks(context.Background())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, order, []string{"c", "a2"})
+}
+
+func Test_ShutdownHooks_Errors(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	ran := false
+	RegisterShutdownHook("last", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+	RegisterShutdownHook("broken", func(ctx context.Context) error {
+		panic("oops")
+	})
+	RegisterShutdownHook("failed", func(ctx context.Context) error {
+		return errors.New("bad")
+	})
+
+	err := RunShutdownHooks(context.Background())
+	assert.Assert(t, err != nil)
+	assert.Assert(t, strings.Contains(err.Error(), "failed: bad"), err.Error())
+	assert.Assert(t, strings.Contains(err.Error(), "broken: panic: oops"), err.Error())
+	assert.Assert(t, ran)
+}
+
+func Test_ShutdownHooks_AsyncWriter(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	memBuffer := &blockingBuffer{}
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+	defer writer.Close(context.Background())
+	RegisterShutdownHook("async", writer.Flush)
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("info_event")
+
+	err := RunShutdownHooks(context.Background())
+	assert.NilError(t, err)
+	assertContainsString(t, memBuffer.String(), "event", "info_event")
+}
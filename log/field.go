@@ -0,0 +1,354 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/helper"
+)
+
+type fieldType uint8
+
+const (
+	stringType fieldType = iota
+	intType
+	floatType
+	boolType
+	durationType
+	timeType
+	errorType
+	objectType
+)
+
+// Field is a single typed property, eg. log.String("user_name", name). Unlike Fields, a list of Field values
+// doesn't need a map, so when writing JSON to a FieldWriter, AsyncWriter or FileWriter the entry is streamed straight
+// into a pooled buffer without building one.
+type Field struct {
+	Key string
+
+	fieldType fieldType
+	integer   int64
+	float     float64
+	str       string
+	iface     interface{}
+}
+
+// String creates a Field with a string value
+func String(key string, value string) Field {
+	return Field{Key: key, fieldType: stringType, str: value}
+}
+
+// Int creates a Field with an int value
+func Int(key string, value int) Field {
+	return Field{Key: key, fieldType: intType, integer: int64(value)}
+}
+
+// Int64 creates a Field with an int64 value
+func Int64(key string, value int64) Field {
+	return Field{Key: key, fieldType: intType, integer: value}
+}
+
+// Float64 creates a Field with a float64 value
+func Float64(key string, value float64) Field {
+	return Field{Key: key, fieldType: floatType, float: value}
+}
+
+// Bool creates a Field with a bool value
+func Bool(key string, value bool) Field {
+	field := Field{Key: key, fieldType: boolType}
+	if value {
+		field.integer = 1
+	}
+	return field
+}
+
+// Duration creates a Field with a duration, written in ISO8601 format (see DurationAsISO8601)
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, fieldType: durationType, integer: int64(value)}
+}
+
+// Timestamp creates a Field with a time, written in UTC using RFC3339Milli
+func Timestamp(key string, value time.Time) Field {
+	return Field{Key: key, fieldType: timeType, integer: value.UnixNano()}
+}
+
+// Err creates an "error" Field with the message of err
+func Err(err error) Field {
+	return Field{Key: "error", fieldType: errorType, iface: err}
+}
+
+// Object creates a Field with any value, written using encoding/json. Prefer the other typed Fields on the hot
+// path as this one allocates.
+func Object(key string, value interface{}) Field {
+	return Field{Key: key, fieldType: objectType, iface: value}
+}
+
+// value returns the Field as it would be stored in Fields
+func (field Field) value() interface{} {
+	switch field.fieldType {
+	case stringType:
+		return field.str
+	case intType:
+		return field.integer
+	case floatType:
+		return field.float
+	case boolType:
+		return field.integer == 1
+	case durationType:
+		return DurationAsISO8601(time.Duration(field.integer))
+	case timeType:
+		return time.Unix(0, field.integer).UTC().Format(RFC3339Milli)
+	case errorType:
+		if err, ok := field.iface.(error); ok && err != nil {
+			return strings.TrimSpace(err.Error())
+		}
+		return nil
+	}
+	return field.iface
+}
+
+func toFields(fields []Field) Fields {
+	converted := make(Fields, len(fields))
+	for _, field := range fields {
+		converted[field.Key] = field.value()
+	}
+	return converted
+}
+
+// DebugWith is the same as Debug, but takes typed Field values eg. logger.DebugWith("cache_miss", log.String("key", key))
+func (logger Logger) DebugWith(event string, fields ...Field) {
+	logger.writeTyped(logger.rsFields, event, nil, DebugSev, fields)
+}
+
+// InfoWith is the same as Info, but takes typed Field values eg. logger.InfoWith("user_created", log.Int("count", n))
+func (logger Logger) InfoWith(event string, fields ...Field) {
+	logger.writeTyped(logger.rsFields, event, nil, InfoSev, fields)
+}
+
+// WarnWith is the same as Warn, but takes typed Field values
+func (logger Logger) WarnWith(event string, fields ...Field) {
+	logger.writeTyped(logger.rsFields, event, nil, WarnSev, fields)
+}
+
+// ErrorWith is the same as Error, but takes typed Field values
+func (logger Logger) ErrorWith(event string, err error, fields ...Field) {
+	logger.writeTyped(logger.rsFields, event, err, ErrorSev, fields)
+}
+
+// FatalWith is the same as Fatal, but takes typed Field values. It calls panic (or see SetFatalPolicy) after writing.
+func (logger Logger) FatalWith(event string, err error, fields ...Field) {
+	event = logger.writeTyped(logger.rsFields, event, err, FatalSev, fields)
+
+	fatal(event, err)
+}
+
+func (logger Logger) writeTyped(rsFields gcontext.RequestScopedFields, event string, err error, sev string, fields []Field) string {
+	event = helper.ToSnakeCase(event)
+
+	if logger.buffer == nil && !sevLevel.shouldLogEvent(event, sev, logger.level) {
+		return event
+	}
+
+	// errors (with their stack trace) and debug buffers are rare enough that they aren't worth a streaming version,
+	// and hooks and schema validation need the entry as Fields
+	lines, ok := logger.writer.(lineWriter)
+	var encoder JSONEncoder
+	if ok {
+		encoder, ok = lines.jsonEncoder()
+	}
+	if !ok || err != nil || logger.buffer != nil || logger.hasHooks() || GetSchema() != nil {
+		// writeTyped is one more frame between write and the caller
+		logger.callerSkip++
+		return logger.write(rsFields, event, err, sev, toFields(fields))
+	}
+
+	if !GetSampler().sample(event, sev) {
+		return event
+	}
+
+	buffer := getLineBuffer()
+	line := logger.appendEntry((*buffer)[:0], &jsonNotes{maxValue: encoder.maxValue()}, rsFields, event, sev, fields)
+	if maxLine := encoder.maxLine(); maxLine > 0 && len(line) > maxLine {
+		// too long, so let the encoder work out what to leave out
+		system, properties := logger.entry(rsFields, event, nil, sev, toFields(fields))
+		line = append(line[:0], serializeFields(encoder, system, properties)...)
+	}
+	lines.writeLine(append(line, '\n'))
+	*buffer = line
+	putLineBuffer(buffer)
+
+	return event
+}
+
+// appendEntry writes the same JSON as JSONEncoder would for this entry, with the keys in the same order
+func (logger Logger) appendEntry(buf []byte, notes *jsonNotes, rsFields gcontext.RequestScopedFields, event string, sev string, fields []Field) []byte {
+	sys := logger.sysValues
+
+	buf = append(buf, '{')
+	buf = appendKey(buf, Time, true)
+	buf = append(buf, '"')
+	buf = time.Now().UTC().AppendFormat(buf, RFC3339Milli)
+	buf = append(buf, '"')
+	buf = appendStringField(buf, Severity, sev, false)
+	buf = appendStringField(buf, Event, notes.capString(Event, event), false)
+	buf = appendStringField(buf, TraceID, notes.capString(TraceID, rsFields.TraceID), false)
+	buf = appendStringField(buf, RequestID, notes.capString(RequestID, rsFields.RequestID), false)
+	buf = appendStringField(buf, CorrelationID, notes.capString(CorrelationID, rsFields.CorrelationID), false)
+	buf = appendStringField(buf, Customer, notes.capString(Customer, rsFields.CustomerAggregateID), false)
+	buf = appendStringField(buf, User, notes.capString(User, rsFields.UserAggregateID), false)
+	buf = appendStringField(buf, Product, notes.capString(Product, os.Getenv(ProductEnv)), false)
+	buf = appendStringField(buf, App, notes.capString(App, os.Getenv(AppEnv)), false)
+	buf = appendStringField(buf, AppVer, notes.capString(AppVer, os.Getenv(AppVerEnv)), false)
+	buf = appendStringField(buf, AwsRegion, notes.capString(AwsRegion, os.Getenv(AwsRegionEnv)), false)
+	buf = appendStringField(buf, AwsAccountID, notes.capString(AwsAccountID, os.Getenv(AwsAcountIDEnv)), false)
+	buf = appendStringField(buf, Resource, notes.capString(Resource, sys.hostName()), false)
+	buf = appendStringField(buf, Os, sys.targetOS(), false)
+	if CallerEnabled() {
+		if caller, ok := callerAt(entrySkip + logger.callerSkip); ok {
+			buf = appendKey(buf, Caller, false)
+			buf = caller.appendJSON(buf)
+		}
+	}
+	buf = logger.appendProperties(buf, notes, fields)
+	buf = notes.appendMarkers(buf, false)
+	return append(buf, '}')
+}
+
+func (logger Logger) appendProperties(buf []byte, notes *jsonNotes, fields []Field) []byte {
+	redactor := GetRedactor()
+
+	mark := len(buf)
+	buf = appendKey(buf, Properties, false)
+	buf = append(buf, '{')
+	start := len(buf)
+
+	// the Logger's own fields first, unless overridden by a typed Field (same as Merge)
+	for key, value := range logger.fields {
+		if hasKey(fields, key) {
+			continue
+		}
+		buf = appendProperty(buf, notes, redactor, key, value, start)
+	}
+
+	for i, field := range fields {
+		if hasKey(fields[i+1:], field.Key) {
+			// last one wins, same as Merge
+			continue
+		}
+		buf = appendField(buf, notes, redactor, field, start)
+	}
+
+	if len(buf) == start {
+		// nothing left (eg. everything was redacted) so leave properties out altogether
+		return buf[:mark]
+	}
+	return append(buf, '}')
+}
+
+func appendField(buf []byte, notes *jsonNotes, redactor *Redactor, field Field, start int) []byte {
+	if field.fieldType == objectType || (redactor != nil && redactor.KeyIsSensitive(field.Key)) {
+		// let the generic path apply key redaction and json encoding
+		return appendProperty(buf, notes, redactor, field.Key, field.value(), start)
+	}
+
+	mark := len(buf)
+	key := helper.ToSnakeCase(field.Key)
+	buf = appendKey(buf, key, len(buf) == start)
+
+	switch field.fieldType {
+	case stringType:
+		return appendRedactedString(buf, notes, redactor, key, field.str, mark)
+	case errorType:
+		err, _ := field.iface.(error)
+		if err == nil {
+			return append(buf, "null"...)
+		}
+		return appendRedactedString(buf, notes, redactor, key, strings.TrimSpace(err.Error()), mark)
+	case intType:
+		return appendInt(buf, field.integer)
+	case floatType:
+		var ok bool
+		if buf, ok = appendFloat(buf, field.float); !ok {
+			notes.encodingError(Properties+"."+key, unsupportedFloat(field.float))
+			return buf[:mark]
+		}
+		return buf
+	case boolType:
+		return appendBool(buf, field.integer == 1)
+	case durationType:
+		buf = append(buf, `"P`...)
+		buf = appendShortestFloat(buf, time.Duration(field.integer).Seconds())
+		return append(buf, `S"`...)
+	case timeType:
+		buf = append(buf, '"')
+		buf = time.Unix(0, field.integer).UTC().AppendFormat(buf, RFC3339Milli)
+		return append(buf, '"')
+	}
+	return buf[:mark]
+}
+
+func appendProperty(buf []byte, notes *jsonNotes, redactor *Redactor, key string, value interface{}, start int) []byte {
+	if redactor != nil {
+		if redactor.KeyIsSensitive(key) {
+			if redactor.conf.Strategy == RedactRemove {
+				return buf
+			}
+			value = redactor.replace(fmt.Sprint(value))
+		} else {
+			var keep bool
+			if value, keep = redactor.redactValue(value); !keep {
+				return buf
+			}
+		}
+	}
+
+	if skipValue(value) {
+		// can't ever be serialized (eg. a func or chan), so leave it out
+		return buf
+	}
+
+	mark := len(buf)
+	key = helper.ToSnakeCase(key)
+	buf = appendKey(buf, key, len(buf) == start)
+
+	if notes.maxValue > 0 && tooLong(value, notes.maxValue) {
+		value = notes.capValue(Properties+"."+key, value)
+	}
+
+	var err error
+	if buf, err = appendValue(buf, value); err != nil {
+		notes.encodingError(Properties+"."+key, err)
+		return buf[:mark]
+	}
+	return buf
+}
+
+func appendRedactedString(buf []byte, notes *jsonNotes, redactor *Redactor, key string, value string, mark int) []byte {
+	if redactor != nil {
+		var keep bool
+		if value, keep = redactor.redactText(value); !keep {
+			return buf[:mark]
+		}
+	}
+	if notes.maxValue > 0 && len(value) > notes.maxValue {
+		value = notes.capString(Properties+"."+key, value)
+	}
+	return appendString(buf, value)
+}
+
+func appendStringField(buf []byte, key string, value string, first bool) []byte {
+	buf = appendKey(buf, key, first)
+	return appendString(buf, value)
+}
+
+func hasKey(fields []Field, key string) bool {
+	for _, field := range fields {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+var timeValue = regexp.MustCompile(`"time":"[^"]*"`)
+
+func Test_Field_SameAsFields(t *testing.T) {
+	fieldsBuffer, fieldsLogger := newBufferLogger()
+	typedBuffer, typedLogger := newBufferLogger()
+
+	fieldsLogger.Info("typed event", Fields{
+		"string":    "<hello> & \"world\"\n\u2028\x01",
+		"int":       123,
+		"float":     42.48,
+		"tiny":      0.0000001,
+		"bool":      true,
+		"duration":  DurationAsISO8601(1500 * time.Millisecond),
+		"userEmail": "bob@example.com",
+		"password":  "abc",
+		"object":    Fields{"nestedKey": []int{1, 2}},
+	})
+
+	// typed fields are written in the order given, so use the sorted order encoding/json gives Fields
+	typedLogger.InfoWith("typed event",
+		Bool("bool", true),
+		Duration("duration", 1500*time.Millisecond),
+		Float64("float", 42.48),
+		Int("int", 123),
+		Object("object", Fields{"nestedKey": []int{1, 2}}),
+		String("password", "abc"),
+		String("string", "<hello> & \"world\"\n\u2028\x01"),
+		Float64("tiny", 0.0000001),
+		String("userEmail", "bob@example.com"),
+	)
+
+	expected := timeValue.ReplaceAllString(fieldsBuffer.String(), `"time":""`)
+	actual := timeValue.ReplaceAllString(typedBuffer.String(), `"time":""`)
+	assert.Equal(t, actual, expected)
+}
+
+func Test_Field_LoggerFieldsAndOverrides(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+	logger := NewWitCustomWriter(rsFields, writer, Fields{"team": "core", "count": 1})
+
+	logger.InfoWith("info_event", Int("count", 2), String("name", "first"), String("name", "second"))
+
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "team", "core")
+	assertContainsInt(t, msg, "count", 2)
+	assertContainsString(t, msg, "name", "second")
+	assert.Assert(t, strings.Count(msg, `"count"`) == 1, msg)
+	assert.Assert(t, strings.Count(msg, `"name"`) == 1, msg)
+}
+
+func Test_Field_NoProperties(t *testing.T) {
+	defer SetRedactor(nil)
+	SetRedactor(NewRedactor())
+
+	memBuffer, logger := newBufferLogger()
+
+	logger.InfoWith("info_event", Object("callback", func() {}))
+	msg := memBuffer.String()
+	assert.Assert(t, !strings.Contains(msg, Properties), msg)
+
+	memBuffer.Reset()
+	logger.InfoWith("info_event", String("api_token", "abc"))
+	assertContainsString(t, memBuffer.String(), "api_token", "[REDACTED]")
+}
+
+func Test_Field_Types(t *testing.T) {
+	memBuffer, logger := newBufferLogger()
+
+	when := time.Date(2020, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	logger.WarnWith("warn_event", Timestamp("started", when), Err(errors.New(" oops ")), Err(nil), Int64("big", 1<<40))
+
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "started", "2020-01-02T03:04:05.006Z")
+	assert.Assert(t, strings.Contains(msg, `"error":null`), msg)
+	assert.Assert(t, strings.Contains(msg, `"big":1099511627776`), msg)
+	assertContainsString(t, msg, "severity", WarnSev)
+}
+
+func Test_Field_ErrorWith(t *testing.T) {
+	memBuffer, logger := newBufferLogger()
+
+	logger.ErrorWith("error_event", errors.New("it broke"), String("string", "hello"))
+
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "event", "error_event")
+	assertContainsString(t, msg, "error", "it broke")
+	assertContainsString(t, msg, "string", "hello")
+}
+
+func Test_Field_OtherEncoder(t *testing.T) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+		conf.Encoder = LogfmtEncoder{}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.InfoWith("info_event", Int("count", 3))
+	assert.Assert(t, strings.Contains(memBuffer.String(), " properties.count=3"), memBuffer.String())
+}
+
+func Test_Field_Level(t *testing.T) {
+	defer SetLevel(DebugSev)
+	SetLevel(WarnSev)
+
+	memBuffer, logger := newBufferLogger()
+	logger.InfoWith("info_event", Int("count", 3))
+	assert.Assert(t, memBuffer.Len() == 0, memBuffer.String())
+}
+
+func Test_Field_FatalWith(t *testing.T) {
+	memBuffer, logger := newBufferLogger()
+
+	defer func() {
+		if r := recover(); r != nil {
+			assertContainsString(t, memBuffer.String(), "event", "fatal_event")
+		}
+	}()
+
+	logger.FatalWith("fatal_event", errors.New("fatal"))
+	t.Errorf("did not panic")
+}
+
+func Test_Field_Allocations(t *testing.T) {
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = ioutil.Discard
+		conf.Encoder = JSONEncoder{}
+	})
+	typedLogger := newLogger(rsFields, writer)
+
+	typed := testing.AllocsPerRun(100, func() {
+		typedLogger.InfoWith("test_details", String("string", "hello"), Int("int", 123), Float64("float", 42.48))
+	})
+	fields := testing.AllocsPerRun(100, func() {
+		typedLogger.Info("test_details", Fields{"string": "hello", "int": 123, "float": 42.48})
+	})
+
+	// typed fields are streamed into a pooled buffer, instead of going through maps and encoding/json
+	assert.Assert(t, typed <= 4, "typed %v allocs", typed)
+	assert.Assert(t, typed*10 < fields, "typed %v allocs, fields %v allocs", typed, fields)
+}
@@ -0,0 +1,429 @@
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const rotatedTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileWriterConfig for setting initial values for FileWriter
+type FileWriterConfig struct {
+	// Filename is the file written to. Rotated files are kept next to it, eg. app-2020-01-02T15-04-05.000.log
+	Filename string
+	// MaxSize rotates the file before a write would take it past this many bytes. 0 turns size rotation off.
+	MaxSize int64
+	// RotateEvery rotates the file once it has been open this long. 0 turns time rotation off.
+	RotateEvery time.Duration
+	// MaxBackups is the number of rotated files kept, the oldest are deleted. 0 keeps them all.
+	MaxBackups int
+	// Compress gzips rotated files
+	Compress bool
+	// ReopenOnSIGHUP closes and reopens Filename when the process receives SIGHUP, so tools like logrotate can
+	// move the file out of the way. It is off by default, as it stops SIGHUP's default behaviour of ending the process.
+	ReopenOnSIGHUP bool
+
+	// Encoder formats each entry, see WriterConfig.Encoder
+	Encoder Encoder
+}
+
+// FileWriter writes entries to a file, rotating it by size and/or time. It is safe for concurrent use by many Loggers.
+// Call Close before the process exits so any rotated file still being compressed is finished.
+type FileWriter struct {
+	mutex   sync.Mutex
+	conf    FileWriterConfig
+	encoder Encoder
+	// file is nil after a rotate couldn't open the new file, and is opened again by the next write
+	file     *os.File
+	closed   bool
+	size     int64
+	openedAt time.Time
+	now      func() time.Time
+	openFile func(name string, flag int, perm os.FileMode) (*os.File, error)
+
+	// rotated files are compressed and removed on a background go routine, one at a time
+	millMutex sync.Mutex
+	milling   sync.WaitGroup
+
+	signals chan os.Signal
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewFileWriter opens (or creates) the file and returns a FileWriter. The optional configure func lets you set the
+// rotation rules. By default the file is rotated at 100MB and 7 rotated files are kept.
+func NewFileWriter(configure ...func(*FileWriterConfig)) (*FileWriter, error) {
+
+	conf := FileWriterConfig{
+		MaxSize:    100 * 1024 * 1024,
+		MaxBackups: 7,
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	if conf.Filename == "" {
+		return nil, fmt.Errorf("FileWriterConfig.Filename must be set")
+	}
+	conf.Filename = filepath.Clean(conf.Filename)
+
+	writer := &FileWriter{
+		conf:     conf,
+		now:      time.Now,
+		openFile: os.OpenFile,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if err := writer.open(); err != nil {
+		return nil, err
+	}
+
+	writer.encoder = conf.Encoder
+	if writer.encoder == nil {
+		writer.encoder = defaultEncoder(writer.file)
+	}
+
+	if conf.ReopenOnSIGHUP {
+		writer.signals = make(chan os.Signal, 1)
+		signal.Notify(writer.signals, syscall.SIGHUP)
+		go writer.handleSignals()
+	} else {
+		close(writer.done)
+	}
+
+	return writer, nil
+}
+
+func (writer *FileWriter) WriteFields(system Fields, fields ...Fields) {
+	str := serializeFields(writer.encoder, system, fields...)
+	writer.writeLine(toLine(str))
+}
+
+func (writer *FileWriter) jsonEncoder() (JSONEncoder, bool) {
+	encoder, ok := writer.encoder.(JSONEncoder)
+	return encoder, ok
+}
+
+func (writer *FileWriter) writeLine(line []byte) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.closed {
+		return
+	}
+	if writer.file == nil {
+		// the last rotate couldn't open the new file, so try again
+		if err := writer.open(); err != nil {
+			return
+		}
+	}
+
+	if writer.shouldRotate(len(line)) {
+		// if this fails we carry on writing to the current file, rather than lose the entry
+		writer.rotate()
+	}
+
+	// This can return an error, but we just swallow it here as what can we or a client really do? Try and log it? :)
+	n, _ := writer.file.Write(line)
+	writer.size += int64(n)
+}
+
+// Rotate closes the current file, renames it with a timestamp and opens a new one
+func (writer *FileWriter) Rotate() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.closed {
+		return fmt.Errorf("file writer is closed")
+	}
+	if writer.file == nil {
+		return writer.open()
+	}
+	return writer.rotate()
+}
+
+// Reopen closes and reopens the file without renaming it. Used after another tool (eg. logrotate) has moved the file.
+func (writer *FileWriter) Reopen() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.closed {
+		return fmt.Errorf("file writer is closed")
+	}
+
+	// keep writing to the old file unless the new one opens
+	previous := writer.file
+	if err := writer.open(); err != nil {
+		return err
+	}
+	if previous != nil {
+		previous.Close()
+	}
+	return nil
+}
+
+// Close stops handling SIGHUP, closes the file and waits for any rotated files to finish compressing. Returns an error
+// if the ctx is done first, and the compressing carries on in the background. Entries written after Close are dropped.
+func (writer *FileWriter) Close(ctx context.Context) error {
+	writer.mutex.Lock()
+	if writer.closed {
+		writer.mutex.Unlock()
+		return nil
+	}
+	var err error
+	if writer.file != nil {
+		err = writer.file.Close()
+	}
+	writer.file = nil
+	writer.closed = true
+	writer.mutex.Unlock()
+
+	if writer.signals != nil {
+		signal.Stop(writer.signals)
+		close(writer.stop)
+	}
+	<-writer.done
+
+	if millErr := writer.waitForMill(ctx); err == nil {
+		err = millErr
+	}
+
+	UnregisterShutdownHook(writer.shutdownName())
+	return err
+}
+
+// waitForMill waits for any rotated files to finish compressing, or the ctx to be done
+func (writer *FileWriter) waitForMill(ctx context.Context) error {
+	milled := make(chan struct{})
+	go func() {
+		writer.milling.Wait()
+		close(milled)
+	}()
+
+	select {
+	case <-milled:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RegisterShutdown registers a shutdown hook (see RegisterShutdownHook) that closes the file when RunShutdownHooks is
+// called or Fatal exits, so rotated files finish compressing if there's time. Close unregisters it.
+func (writer *FileWriter) RegisterShutdown() {
+	RegisterShutdownHook(writer.shutdownName(), writer.Close)
+}
+
+func (writer *FileWriter) shutdownName() string {
+	return fmt.Sprintf("log.FileWriter(%p)", writer)
+}
+
+func (writer *FileWriter) handleSignals() {
+	defer close(writer.done)
+
+	for {
+		select {
+		case <-writer.signals:
+			writer.Reopen()
+		case <-writer.stop:
+			return
+		}
+	}
+}
+
+func (writer *FileWriter) shouldRotate(length int) bool {
+	if writer.conf.MaxSize > 0 && writer.size > 0 && writer.size+int64(length) > writer.conf.MaxSize {
+		return true
+	}
+	if writer.conf.RotateEvery > 0 && !writer.now().Before(writer.openedAt.Add(writer.conf.RotateEvery)) {
+		return true
+	}
+	return false
+}
+
+// open must be called with the mutex held
+func (writer *FileWriter) open() error {
+	if dir := filepath.Dir(writer.conf.Filename); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	file, err := writer.openFile(writer.conf.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	writer.file = file
+	writer.size = info.Size()
+	writer.openedAt = writer.now()
+	return nil
+}
+
+// rotate must be called with the mutex held. The file has to be closed before it is renamed (on Windows), so if the
+// new file can't be opened the writer is left without one, and the next write tries to open it again.
+func (writer *FileWriter) rotate() error {
+	if err := writer.file.Close(); err != nil {
+		return err
+	}
+	writer.file = nil
+
+	rotated := writer.rotatedName(writer.now())
+	if err := os.Rename(writer.conf.Filename, rotated); err != nil {
+		// reopen what we had, so we can still write
+		writer.open()
+		return err
+	}
+
+	if err := writer.open(); err != nil {
+		return err
+	}
+
+	writer.milling.Add(1)
+	go writer.mill(rotated)
+	return nil
+}
+
+// rotatedName returns a name for the rotated file that isn't used yet, eg. app-2020-01-02T15-04-05.000.log
+func (writer *FileWriter) rotatedName(now time.Time) string {
+	prefix, ext := writer.nameParts()
+	name := prefix + now.UTC().Format(rotatedTimeFormat)
+
+	rotated := name + ext
+	for i := 1; writer.exists(rotated) || writer.exists(rotated+".gz"); i++ {
+		rotated = fmt.Sprintf("%s.%d%s", name, i, ext)
+	}
+	return rotated
+}
+
+func (writer *FileWriter) nameParts() (string, string) {
+	ext := filepath.Ext(writer.conf.Filename)
+	return strings.TrimSuffix(writer.conf.Filename, ext) + "-", ext
+}
+
+func (writer *FileWriter) exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// mill compresses a rotated file (if configured) and removes the oldest rotated files
+func (writer *FileWriter) mill(rotated string) {
+	defer writer.milling.Done()
+
+	writer.millMutex.Lock()
+	defer writer.millMutex.Unlock()
+
+	if writer.conf.Compress {
+		compressFile(rotated)
+	}
+
+	if writer.conf.MaxBackups > 0 {
+		backups := writer.backups()
+		for len(backups) > writer.conf.MaxBackups {
+			os.Remove(backups[0])
+			backups = backups[1:]
+		}
+	}
+}
+
+// backups returns the rotated files, oldest first
+func (writer *FileWriter) backups() []string {
+	prefix, ext := writer.nameParts()
+	dir := filepath.Dir(writer.conf.Filename)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	type backup struct {
+		name    string
+		stamp   string
+		counter int
+	}
+
+	var backups []backup
+	for _, file := range files {
+		name := filepath.Join(dir, file.Name())
+		if file.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		// eg. 2020-01-02T15-04-05.000 or 2020-01-02T15-04-05.000.1 after a collision
+		stamp := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSuffix(name, ".gz"), prefix), ext)
+		if len(stamp) < len(rotatedTimeFormat) {
+			continue
+		}
+		if _, err := time.Parse(rotatedTimeFormat, stamp[:len(rotatedTimeFormat)]); err != nil {
+			continue
+		}
+
+		counter := 0
+		if rest := stamp[len(rotatedTimeFormat):]; rest != "" {
+			if counter, err = strconv.Atoi(strings.TrimPrefix(rest, ".")); err != nil || !strings.HasPrefix(rest, ".") {
+				continue
+			}
+		}
+		backups = append(backups, backup{name: name, stamp: stamp[:len(rotatedTimeFormat)], counter: counter})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].stamp != backups[j].stamp {
+			return backups[i].stamp < backups[j].stamp
+		}
+		return backups[i].counter < backups[j].counter
+	})
+
+	names := make([]string, len(backups))
+	for i, b := range backups {
+		names[i] = b.name
+	}
+	return names
+}
+
+func compressFile(name string) error {
+	source, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	zipper := gzip.NewWriter(target)
+	_, err = io.Copy(zipper, source)
+	if err == nil {
+		err = zipper.Close()
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	return os.Remove(name)
+}
@@ -0,0 +1,327 @@
+package log
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func newTestFileWriter(t *testing.T, configure func(*FileWriterConfig)) (*FileWriter, string) {
+	dir, err := ioutil.TempDir("", "filewriter")
+	assert.NilError(t, err)
+
+	writer, err := NewFileWriter(func(conf *FileWriterConfig) {
+		conf.Filename = filepath.Join(dir, "app.log")
+		configure(conf)
+	})
+	assert.NilError(t, err)
+	return writer, dir
+}
+
+func readDir(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	assert.NilError(t, err)
+
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	return names
+}
+
+func Test_FileWriter_Write(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {})
+	defer os.RemoveAll(dir)
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("info_event", Fields{"string": "hello"})
+	logger.InfoWith("typed_event", String("string", "world"))
+	assert.NilError(t, writer.Close(context.Background()))
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NilError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(buf)), "\n")
+	assert.Assert(t, len(lines) == 2, lines)
+	assertContainsString(t, lines[0], "event", "info_event")
+	assertContainsString(t, lines[1], "string", "world")
+
+	// writes after close are dropped, not a panic
+	logger.Info("dropped_event")
+	assert.NilError(t, writer.Close(context.Background()))
+}
+
+func Test_FileWriter_RotateBySize(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {
+		conf.MaxSize = 1024
+		conf.MaxBackups = 2
+	})
+	defer os.RemoveAll(dir)
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	for i := 0; i < 20; i++ {
+		logger.Info("info_event", Fields{"index": i})
+	}
+	assert.NilError(t, writer.Close(context.Background()))
+
+	names := readDir(t, dir)
+	assert.Assert(t, len(names) == 3, names)
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(dir, name))
+		assert.NilError(t, err)
+		assert.Assert(t, info.Size() <= 1024, "%s is %d bytes", name, info.Size())
+	}
+
+	// the newest entry is in the current file
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), `"index":19`), string(buf))
+}
+
+func Test_FileWriter_RotateByTime(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {
+		conf.MaxSize = 0
+		conf.RotateEvery = time.Hour
+	})
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	writer.now = func() time.Time { return now }
+	writer.openedAt = now
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("first_event")
+	now = now.Add(59 * time.Minute)
+	logger.Info("second_event")
+	now = now.Add(time.Minute)
+	logger.Info("third_event")
+	assert.NilError(t, writer.Close(context.Background()))
+
+	names := readDir(t, dir)
+	assert.DeepEqual(t, names, []string{"app-2020-01-02T04-04-05.000.log", "app.log"})
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, names[0]))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), "second_event"), string(buf))
+	assert.Assert(t, !strings.Contains(string(buf), "third_event"), string(buf))
+}
+
+func Test_FileWriter_Compress(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {
+		conf.Compress = true
+	})
+	defer os.RemoveAll(dir)
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("before_rotate")
+	assert.NilError(t, writer.Rotate())
+	logger.Info("after_rotate")
+	assert.NilError(t, writer.Close(context.Background()))
+
+	names := readDir(t, dir)
+	assert.Assert(t, len(names) == 2, names)
+	assert.Assert(t, strings.HasSuffix(names[0], ".log.gz"), names)
+
+	file, err := os.Open(filepath.Join(dir, names[0]))
+	assert.NilError(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	assert.NilError(t, err)
+	buf, err := ioutil.ReadAll(reader)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), "before_rotate"), string(buf))
+}
+
+func Test_FileWriter_Backups(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {
+		conf.MaxBackups = 2
+	})
+	defer os.RemoveAll(dir)
+
+	// rotating within the same millisecond needs a counter so nothing is overwritten
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	writer.now = func() time.Time { return now }
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	for _, event := range []string{"first", "second", "third"} {
+		logger.Info(event)
+		assert.NilError(t, writer.Rotate())
+		writer.milling.Wait()
+	}
+	assert.NilError(t, writer.Close(context.Background()))
+
+	names := readDir(t, dir)
+	assert.DeepEqual(t, names, []string{"app-2020-01-02T03-04-05.000.1.log", "app-2020-01-02T03-04-05.000.2.log", "app.log"})
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, names[1]))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), "third"), string(buf))
+}
+
+func Test_FileWriter_Reopen(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {})
+	defer os.RemoveAll(dir)
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("before_move")
+
+	// what logrotate does
+	assert.NilError(t, os.Rename(filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.1")))
+	assert.NilError(t, writer.Reopen())
+
+	logger.Info("after_move")
+	assert.NilError(t, writer.Close(context.Background()))
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), "after_move"), string(buf))
+	assert.Assert(t, !strings.Contains(string(buf), "before_move"), string(buf))
+}
+
+func Test_FileWriter_ReopenFails(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {})
+	defer os.RemoveAll(dir)
+	assert.Assert(t, !writer.conf.ReopenOnSIGHUP)
+
+	writer.openFile = func(string, int, os.FileMode) (*os.File, error) {
+		return nil, errors.New("too many open files")
+	}
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	assert.Error(t, writer.Reopen(), "too many open files")
+
+	// the old file is kept, so nothing is lost
+	logger.Info("after_failed_reopen")
+	assert.NilError(t, writer.Close(context.Background()))
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), "after_failed_reopen"), string(buf))
+}
+
+func Test_FileWriter_RotateOpenFails(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {
+		conf.MaxBackups = 0
+	})
+	defer os.RemoveAll(dir)
+
+	failing := true
+	writer.openFile = func(name string, flag int, perm os.FileMode) (*os.File, error) {
+		if failing {
+			return nil, errors.New("too many open files")
+		}
+		return os.OpenFile(name, flag, perm)
+	}
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("before_rotate")
+	assert.Error(t, writer.Rotate(), "too many open files")
+
+	// there's no file to write to yet, so this is dropped rather than a panic
+	logger.Info("dropped_event")
+
+	// the next write opens the file again
+	failing = false
+	logger.Info("after_rotate")
+	assert.NilError(t, writer.Close(context.Background()))
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), "after_rotate"), string(buf))
+	assert.Assert(t, !strings.Contains(string(buf), "dropped_event"), string(buf))
+	assert.Assert(t, len(readDir(t, dir)) == 2, readDir(t, dir))
+}
+
+func Test_FileWriter_Concurrent(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {
+		conf.MaxSize = 4096
+		conf.MaxBackups = 0
+	})
+	defer os.RemoveAll(dir)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := NewWitCustomWriter(rsFields, writer)
+			for j := 0; j < 50; j++ {
+				logger.Info("info_event", Fields{"index": j})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.NilError(t, writer.Close(context.Background()))
+
+	count := 0
+	for _, name := range readDir(t, dir) {
+		buf, err := ioutil.ReadFile(filepath.Join(dir, name))
+		assert.NilError(t, err)
+		for _, line := range strings.Split(strings.TrimSpace(string(buf)), "\n") {
+			assert.Assert(t, strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}"), line)
+			count++
+		}
+	}
+	assert.Assert(t, count == 200, count)
+}
+
+func Test_FileWriter_NoFilename(t *testing.T) {
+	writer, err := NewFileWriter()
+	assert.Assert(t, writer == nil, writer)
+	assert.Assert(t, err != nil, err)
+}
+
+func Test_FileWriter_RegisterShutdown(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {})
+	defer os.RemoveAll(dir)
+	writer.RegisterShutdown()
+	assert.Assert(t, len(shutdownHookNames()) == 1, shutdownHookNames())
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("info_event")
+
+	err := RunShutdownHooks(context.Background())
+	assert.NilError(t, err)
+	assert.Assert(t, len(shutdownHookNames()) == 0, shutdownHookNames())
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NilError(t, err)
+	assertContainsString(t, string(buf), "event", "info_event")
+
+	// closed by the hook, so later entries are dropped
+	logger.Info("dropped_event")
+	buf, _ = ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.Assert(t, !strings.Contains(string(buf), "dropped_event"), string(buf))
+}
+
+func Test_FileWriter_CloseDeadline(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {})
+	defer os.RemoveAll(dir)
+
+	// a rotated file that takes a long time to compress
+	writer.milling.Add(1)
+	defer writer.milling.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := writer.Close(ctx)
+	assert.Assert(t, err == context.DeadlineExceeded, err)
+	assert.Assert(t, time.Since(start) < time.Second, time.Since(start))
+
+	// the file itself is still closed
+	assert.Error(t, writer.Rotate(), "file writer is closed")
+}
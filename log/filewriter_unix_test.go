@@ -0,0 +1,46 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func Test_FileWriter_SIGHUP(t *testing.T) {
+	writer, dir := newTestFileWriter(t, func(conf *FileWriterConfig) {
+		conf.ReopenOnSIGHUP = true
+	})
+	defer os.RemoveAll(dir)
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("before_move")
+
+	assert.NilError(t, os.Rename(filepath.Join(dir, "app.log"), filepath.Join(dir, "app.log.1")))
+	assert.NilError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	// the signal is handled on another go routine
+	reopened := false
+	for i := 0; i < 100 && !reopened; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, err := os.Stat(filepath.Join(dir, "app.log"))
+		reopened = err == nil
+	}
+	assert.Assert(t, reopened)
+
+	logger.Info("after_move")
+	assert.NilError(t, writer.Close(context.Background()))
+
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(buf), "after_move"), string(buf))
+}
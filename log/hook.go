@@ -0,0 +1,88 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hook is called with every entry before it is written, so it can enrich the entry (eg. add a trace id), count it
+// (eg. a metric per ERROR) or forward it somewhere else (eg. notify.NewLogHook). system and properties are the entry
+// being written, so a hook can add, change or delete keys. Return false to veto the entry, it is then not written and
+// no later hooks are called.
+//
+// Hooks are called on the logging go routine, so they must be quick and safe for concurrent use.
+type Hook interface {
+	Fire(severity string, event string, system Fields, properties Fields) bool
+}
+
+// HookFunc lets an ordinary func be used as a Hook
+type HookFunc func(severity string, event string, system Fields, properties Fields) bool
+
+// Fire calls f
+func (f HookFunc) Fire(severity string, event string, system Fields, properties Fields) bool {
+	return f(severity, event, system, properties)
+}
+
+var (
+	// the global hooks are copied on write, so logging doesn't need to take the lock
+	globalHooks atomic.Value
+	hooksWrite  sync.Mutex
+)
+
+func init() {
+	globalHooks.Store([]Hook{})
+}
+
+// AddHook registers a hook that is called for the entries of every Logger. Global hooks are called in the order
+// they were added, before the hooks of the Logger itself (see Logger.WithHooks).
+func AddHook(hook Hook) {
+	hooksWrite.Lock()
+	defer hooksWrite.Unlock()
+
+	current := getHooks()
+	updated := make([]Hook, len(current), len(current)+1)
+	copy(updated, current)
+	globalHooks.Store(append(updated, hook))
+}
+
+// ClearHooks removes every global hook
+func ClearHooks() {
+	hooksWrite.Lock()
+	defer hooksWrite.Unlock()
+
+	globalHooks.Store([]Hook{})
+}
+
+func getHooks() []Hook {
+	hooks, _ := globalHooks.Load().([]Hook)
+	return hooks
+}
+
+// WithHooks returns a child Logger that also calls hooks for its entries, after the global hooks and the hooks this
+// Logger already has. This Logger is not changed.
+func (logger Logger) WithHooks(hooks ...Hook) *Logger {
+	merged := make([]Hook, 0, len(logger.hooks)+len(hooks))
+	merged = append(merged, logger.hooks...)
+	logger.hooks = append(merged, hooks...)
+	return &logger
+}
+
+// hasHooks is true if entries written by this Logger need to go through fireHooks
+func (logger Logger) hasHooks() bool {
+	return len(logger.hooks) > 0 || len(getHooks()) > 0
+}
+
+// fireHooks calls the global hooks and then the Logger's own, in order. It returns false if a hook vetoed the entry.
+func (logger Logger) fireHooks(sev string, event string, system Fields, properties Fields) bool {
+	for _, hook := range getHooks() {
+		if !hook.Fire(sev, event, system, properties) {
+			return false
+		}
+	}
+	for _, hook := range logger.hooks {
+		if !hook.Fire(sev, event, system, properties) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_Hook_Enrich(t *testing.T) {
+	defer ClearHooks()
+	AddHook(HookFunc(func(severity string, event string, system Fields, properties Fields) bool {
+		system[TraceID] = "hooked-trace"
+		properties["hooked"] = severity
+		delete(properties, "secret")
+		return true
+	}))
+
+	memBuffer, logger := newBufferLogger()
+
+	logger.Info("info_event", Fields{"secret": "abc"})
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "trace_id", "hooked-trace")
+	assertContainsString(t, msg, "hooked", "INFO")
+	assert.Assert(t, !strings.Contains(msg, "secret"), msg)
+
+	memBuffer.Reset()
+	logger.InfoWith("info_event", String("string", "hello"))
+	msg = memBuffer.String()
+	assertContainsString(t, msg, "hooked", "INFO")
+	assertContainsString(t, msg, "string", "hello")
+}
+
+func Test_Hook_Veto(t *testing.T) {
+	defer ClearHooks()
+
+	called := 0
+	AddHook(HookFunc(func(severity string, event string, system Fields, properties Fields) bool {
+		return event != "vetoed_event"
+	}))
+	AddHook(HookFunc(func(severity string, event string, system Fields, properties Fields) bool {
+		called++
+		return true
+	}))
+
+	memBuffer, logger := newBufferLogger()
+
+	logger.Warn("vetoed_event")
+	assert.Equal(t, memBuffer.String(), "")
+	assert.Equal(t, called, 0)
+
+	logger.Warn("kept_event")
+	assertContainsString(t, memBuffer.String(), "event", "kept_event")
+	assert.Equal(t, called, 1)
+}
+
+func Test_Hook_Order(t *testing.T) {
+	defer ClearHooks()
+
+	var order []string
+	hook := func(name string) Hook {
+		return HookFunc(func(severity string, event string, system Fields, properties Fields) bool {
+			order = append(order, name)
+			return true
+		})
+	}
+
+	AddHook(hook("global1"))
+	AddHook(hook("global2"))
+
+	_, logger := newBufferLogger()
+	parent := logger.WithHooks(hook("parent"))
+	child := parent.WithHooks(hook("child"))
+
+	child.Error("error_event", errors.New("bad"))
+	assert.DeepEqual(t, order, []string{"global1", "global2", "parent", "child"})
+
+	// the parent doesn't get the child's hooks
+	order = nil
+	parent.Info("info_event")
+	assert.DeepEqual(t, order, []string{"global1", "global2", "parent"})
+
+	// nor does the original
+	order = nil
+	logger.Info("info_event")
+	assert.DeepEqual(t, order, []string{"global1", "global2"})
+}
+
+func Test_Hook_NotCalledBelowLevel(t *testing.T) {
+	defer ClearHooks()
+
+	called := false
+	_, logger := newBufferLogger()
+	logger = logger.WithHooks(HookFunc(func(severity string, event string, system Fields, properties Fields) bool {
+		called = true
+		return true
+	}))
+
+	logger.SetLevel(WarnSev)
+	logger.Info("info_event")
+	assert.Assert(t, !called)
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// JournaldSocket is where systemd-journald listens for its native protocol
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldWriterConfig for setting initial values for JournaldWriter
+type JournaldWriterConfig struct {
+	// SocketPath defaults to JournaldSocket
+	SocketPath string
+	// Identifier is SYSLOG_IDENTIFIER, and defaults to the "app" system value (APP), otherwise the name of the executable
+	Identifier string
+}
+
+// JournaldWriter writes entries to systemd-journald using its native protocol, so every system value and property is
+// a journal field (eg. properties.user_id => PROPERTIES_USER_ID) that journalctl can filter on. MESSAGE is the event
+// followed by any "message" property, and PRIORITY is the syslog severity. It is safe for concurrent use.
+//
+// Entries are sent as single datagrams, so one bigger than the socket's maximum datagram size is dropped.
+type JournaldWriter struct {
+	mutex sync.Mutex
+	conf  JournaldWriterConfig
+	conn  *net.UnixConn
+	addr  *net.UnixAddr
+}
+
+// NewJournaldWriter returns a JournaldWriter. The optional configure func lets you set the socket path and identifier.
+func NewJournaldWriter(configure ...func(*JournaldWriterConfig)) (*JournaldWriter, error) {
+
+	conf := JournaldWriterConfig{
+		SocketPath: JournaldSocket,
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	addr := &net.UnixAddr{Name: conf.SocketPath, Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+
+	// fail early if nothing is listening
+	if _, err := os.Stat(conf.SocketPath); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &JournaldWriter{
+		conf: conf,
+		conn: conn,
+		addr: addr,
+	}, nil
+}
+
+func (writer *JournaldWriter) WriteFields(system Fields, fields ...Fields) {
+	msg := writer.format(system, fields...)
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.conn == nil {
+		return
+	}
+
+	// This can return an error, but we just swallow it here as what can we or a client really do? Try and log it? :)
+	writer.conn.WriteToUnix(msg, writer.addr)
+}
+
+// Close closes the socket
+func (writer *JournaldWriter) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.conn == nil {
+		return nil
+	}
+	err := writer.conn.Close()
+	writer.conn = nil
+	return err
+}
+
+func (writer *JournaldWriter) format(system Fields, fields ...Fields) []byte {
+	entry := entryFields(system, fields...)
+
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", entryMessage(entry))
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(SyslogSeverity(fieldString(entry[Severity]))))
+	appendJournalField(&buf, "SYSLOG_IDENTIFIER", writer.identifier(entry))
+
+	flat := map[string]interface{}{}
+	flatten("", entry, flat)
+	for _, key := range sortedKeys(flat) {
+		value := fieldString(flat[key])
+		if value == "" {
+			continue
+		}
+		appendJournalField(&buf, journalFieldName(key), value)
+	}
+
+	return buf.Bytes()
+}
+
+func (writer *JournaldWriter) identifier(entry Fields) string {
+	if writer.conf.Identifier != "" {
+		return writer.conf.Identifier
+	}
+	if app := fieldString(entry[App]); app != "" {
+		return app
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// appendJournalField writes NAME=value\n, or for values with a new line NAME\n<64 bit little endian length>value\n
+func appendJournalField(buf *bytes.Buffer, name string, value string) {
+	buf.WriteString(name)
+	if !strings.ContainsRune(value, '\n') {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName returns a valid journal field name: upper case letters, digits and '_', not starting with '_' or a digit
+func journalFieldName(key string) string {
+	var sb strings.Builder
+	for i := 0; i < len(key) && sb.Len() < 64; i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			c = '_'
+		}
+		if sb.Len() == 0 && (c == '_' || (c >= '0' && c <= '9')) {
+			// fields starting with '_' are trusted fields set by journald itself
+			sb.WriteString("F")
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,90 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func Test_JournaldWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "socket")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	assert.NilError(t, err)
+	defer listener.Close()
+
+	writer, err := NewJournaldWriter(func(conf *JournaldWriterConfig) {
+		conf.SocketPath = socket
+	})
+	assert.NilError(t, err)
+	defer writer.Close()
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Error("request_failed", errors.New("it broke"), Fields{"userID": 123, "_private": "yes"})
+
+	buf := make([]byte, 65536)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := listener.Read(buf)
+	assert.NilError(t, err)
+
+	fields := parseJournal(t, buf[:n])
+	assert.Equal(t, fields["MESSAGE"], "request_failed")
+	assert.Equal(t, fields["PRIORITY"], "3")
+	assert.Equal(t, fields["SYSLOG_IDENTIFIER"], "murmur")
+	assert.Equal(t, fields["TRACE_ID"], "1-2-3")
+	assert.Equal(t, fields["PROPERTIES_USER_ID"], "123")
+	assert.Equal(t, fields["PROPERTIES__PRIVATE"], "yes")
+	assert.Equal(t, fields["EXCEPTION_ERROR"], "it broke")
+	// multi line values use the binary format
+	assert.Assert(t, bytes.Contains([]byte(fields["EXCEPTION_TRACE"]), []byte("\n")), fields["EXCEPTION_TRACE"])
+}
+
+func Test_JournaldWriter_NoSocket(t *testing.T) {
+	writer, err := NewJournaldWriter(func(conf *JournaldWriterConfig) {
+		conf.SocketPath = "/does/not/exist"
+	})
+	assert.Assert(t, writer == nil, writer)
+	assert.Assert(t, err != nil, err)
+}
+
+func Test_JournalFieldName(t *testing.T) {
+	assert.Equal(t, journalFieldName("properties.user_id"), "PROPERTIES_USER_ID")
+	assert.Equal(t, journalFieldName("_secret"), "F_SECRET")
+	assert.Equal(t, journalFieldName("1st"), "F1ST")
+}
+
+// parseJournal reads the journald native protocol
+func parseJournal(t *testing.T, buf []byte) map[string]string {
+	fields := map[string]string{}
+	for len(buf) > 0 {
+		line := bytes.IndexByte(buf, '\n')
+		assert.Assert(t, line >= 0, string(buf))
+
+		if eq := bytes.IndexByte(buf[:line], '='); eq >= 0 {
+			fields[string(buf[:eq])] = string(buf[eq+1 : line])
+			buf = buf[line+1:]
+			continue
+		}
+
+		name := string(buf[:line])
+		buf = buf[line+1:]
+		length := binary.LittleEndian.Uint64(buf[:8])
+		fields[name] = string(buf[8 : 8+length])
+		buf = buf[8+length+1:]
+	}
+	return fields
+}
@@ -0,0 +1,400 @@
+package log
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultMaxValueLength is the longest (in bytes) a string value can be before JSONEncoder truncates it
+	DefaultMaxValueLength = 16 * 1024
+	// DefaultMaxLineLength is the longest (in bytes) JSONEncoder lets an entry be. It is below the 256KB CloudWatch Logs
+	// limit, so log shippers never cut an entry off mid-JSON.
+	DefaultMaxLineLength = 250 * 1024
+
+	// Truncated lists the values (eg. properties.payload) that were cut short or left out to keep the entry within
+	// the JSONEncoder limits
+	Truncated = "_truncated"
+	// EncodingErrors lists the values that couldn't be encoded as JSON (eg. NaN), and were left out
+	EncodingErrors = "_encoding_errors"
+
+	truncatedSuffix = "..."
+)
+
+// systemKeyOrder is the order the system values are written in, before any other keys. The exception and properties
+// are always written last.
+var systemKeyOrder = []string{
+	Time, Severity, Event, TraceID, RequestID, CorrelationID, Customer, User,
+	Product, App, AppVer, AwsRegion, AwsAccountID, Resource, Os, Caller,
+}
+
+var systemKeys = func() map[string]bool {
+	keys := map[string]bool{Exception: true, Properties: true}
+	for _, key := range systemKeyOrder {
+		keys[key] = true
+	}
+	return keys
+}()
+
+func (encoder JSONEncoder) maxValue() int {
+	return jsonLimit(encoder.MaxValueLength, DefaultMaxValueLength)
+}
+
+func (encoder JSONEncoder) maxLine() int {
+	return jsonLimit(encoder.MaxLineLength, DefaultMaxLineLength)
+}
+
+// jsonLimit returns the default for 0, and no limit (0) for a negative value
+func jsonLimit(limit int, def int) int {
+	switch {
+	case limit == 0:
+		return def
+	case limit < 0:
+		return 0
+	}
+	return limit
+}
+
+// jsonNotes collects what had to be changed to encode an entry, so it can be written with the entry
+type jsonNotes struct {
+	maxValue  int
+	truncated []string
+	errors    []string
+}
+
+// jsonMember is a top level key of an entry. Objects (eg. properties) keep each of their members separately, so the
+// biggest can be left out if the entry is too long.
+type jsonMember struct {
+	key     string
+	raw     []byte
+	members []jsonMember
+	object  bool
+}
+
+// encodeJSON writes fields as JSON, with the system values first (see systemKeyOrder), then any other keys sorted,
+// then the exception and properties. Nested keys are sorted. Strings longer than maxValue are truncated, and if the
+// entry is still longer than maxLine the biggest properties are left out until it fits. A limit of 0 is no limit.
+func encodeJSON(fields Fields, maxValue int, maxLine int) string {
+	notes := &jsonNotes{maxValue: maxValue}
+
+	var members []jsonMember
+	for _, key := range jsonKeyOrder(fields) {
+		if member, ok := notes.member(key, fields[key]); ok {
+			members = append(members, member)
+		}
+	}
+
+	// the line is copied into the string we return, so the buffer can be used again by the next entry
+	buffer := getLineBuffer()
+	line := notes.appendEntry((*buffer)[:0], members)
+	for maxLine > 0 && len(line) > maxLine {
+		var dropped bool
+		if members, dropped = notes.dropLargest(members); !dropped {
+			// nothing left to drop, so just say what happened
+			members = notes.minimal(members)
+			line = notes.appendEntry(line[:0], members)
+			break
+		}
+		line = notes.appendEntry(line[:0], members)
+	}
+
+	str := string(line)
+	*buffer = line
+	putLineBuffer(buffer)
+	return str
+}
+
+func jsonKeyOrder(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for _, key := range systemKeyOrder {
+		if _, ok := fields[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	others := make([]string, 0, len(fields)-len(keys))
+	for key := range fields {
+		if !systemKeys[key] {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	keys = append(keys, others...)
+
+	for _, key := range []string{Exception, Properties} {
+		if _, ok := fields[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (notes *jsonNotes) member(key string, value interface{}) (jsonMember, bool) {
+	if skipValue(value) {
+		return jsonMember{}, false
+	}
+
+	object, ok := value.(Fields)
+	if !ok {
+		raw, ok := notes.encode(key, value)
+		return jsonMember{key: key, raw: raw}, ok
+	}
+
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	member := jsonMember{key: key, object: true}
+	for _, k := range keys {
+		if skipValue(object[k]) {
+			continue
+		}
+		if raw, ok := notes.encode(key+"."+k, object[k]); ok {
+			member.members = append(member.members, jsonMember{key: k, raw: raw})
+		}
+	}
+
+	// leave out an object that had nothing we could write, same as an empty properties
+	return member, len(member.members) > 0 || len(object) == 0
+}
+
+// encode returns value as JSON with long strings truncated, or false if it can't be encoded
+func (notes *jsonNotes) encode(path string, value interface{}) ([]byte, bool) {
+	value = notes.capValue(path, value)
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		notes.encodingError(path, err)
+		return nil, false
+	}
+	return raw, true
+}
+
+// encodingError notes that the value at path couldn't be encoded, and was left out
+func (notes *jsonNotes) encodingError(path string, err error) {
+	notes.errors = append(notes.errors, path+": "+err.Error())
+}
+
+// capValue truncates strings longer than maxValue, including any in nested Fields, maps and slices
+func (notes *jsonNotes) capValue(path string, value interface{}) interface{} {
+	if notes.maxValue <= 0 || !tooLong(value, notes.maxValue) {
+		// the common case, so don't copy anything
+		return value
+	}
+
+	switch v := value.(type) {
+	case string:
+		return notes.capString(path, v)
+	case Fields:
+		capped := make(Fields, len(v))
+		for k, nested := range v {
+			capped[k] = notes.capValue(path+"."+k, nested)
+		}
+		return capped
+	case map[string]interface{}:
+		capped := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			capped[k] = notes.capValue(path+"."+k, nested)
+		}
+		return capped
+	case []interface{}:
+		capped := make([]interface{}, len(v))
+		for i, nested := range v {
+			capped[i] = notes.capValue(path+"["+strconv.Itoa(i)+"]", nested)
+		}
+		return capped
+	case []string:
+		capped := make([]string, len(v))
+		for i, nested := range v {
+			capped[i] = notes.capString(path+"["+strconv.Itoa(i)+"]", nested)
+		}
+		return capped
+	case []Fields:
+		capped := make([]Fields, len(v))
+		for i, nested := range v {
+			capped[i], _ = notes.capValue(path+"["+strconv.Itoa(i)+"]", nested).(Fields)
+		}
+		return capped
+	}
+	return value
+}
+
+func (notes *jsonNotes) capString(path string, value string) string {
+	if notes.maxValue <= 0 || len(value) <= notes.maxValue {
+		return value
+	}
+	notes.truncated = append(notes.truncated, path)
+	return truncateString(value, notes.maxValue)
+}
+
+// tooLong is true if value is (or contains) a string longer than max
+func tooLong(value interface{}, max int) bool {
+	switch v := value.(type) {
+	case string:
+		return len(v) > max
+	case Fields:
+		for _, nested := range v {
+			if tooLong(nested, max) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, nested := range v {
+			if tooLong(nested, max) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, nested := range v {
+			if tooLong(nested, max) {
+				return true
+			}
+		}
+	case []string:
+		for _, nested := range v {
+			if len(nested) > max {
+				return true
+			}
+		}
+	case []Fields:
+		for _, nested := range v {
+			if tooLong(nested, max) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// truncateString cuts value to at most max bytes (on a rune boundary), ending with "..."
+func truncateString(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+
+	cut := max - len(truncatedSuffix)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return value[:cut] + truncatedSuffix
+}
+
+// skipValue is true for values that can never be JSON (eg. funcs and chans), which are left out without a note
+func skipValue(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return true
+	}
+	return false
+}
+
+// dropLargest leaves out the biggest member of an object (except the exception's error and type) or the biggest top
+// level value that isn't a system value, and returns false if there is nothing left to drop
+func (notes *jsonNotes) dropLargest(members []jsonMember) ([]jsonMember, bool) {
+	top, nested, size := -1, -1, -1
+
+	for i, member := range members {
+		if !member.object {
+			if !systemKeys[member.key] && len(member.raw) > size {
+				top, nested, size = i, -1, len(member.raw)
+			}
+			continue
+		}
+		for j, child := range member.members {
+			if member.key == Exception && (child.key == "error" || child.key == "type") {
+				continue
+			}
+			if len(child.raw) > size {
+				top, nested, size = i, j, len(child.raw)
+			}
+		}
+	}
+
+	switch {
+	case top < 0:
+		return members, false
+	case nested < 0:
+		notes.truncated = append(notes.truncated, members[top].key)
+		return append(members[:top], members[top+1:]...), true
+	}
+
+	object := &members[top]
+	notes.truncated = append(notes.truncated, object.key+"."+object.members[nested].key)
+	object.members = append(object.members[:nested], object.members[nested+1:]...)
+	return members, true
+}
+
+// minimal keeps just the time, severity and event
+func (notes *jsonNotes) minimal(members []jsonMember) []jsonMember {
+	var kept []jsonMember
+	for _, member := range members {
+		switch member.key {
+		case Time, Severity, Event:
+			kept = append(kept, member)
+		default:
+			notes.truncated = append(notes.truncated, member.key)
+		}
+	}
+	return kept
+}
+
+func (notes *jsonNotes) appendEntry(buf []byte, members []jsonMember) []byte {
+	buf = append(buf, '{')
+	for i, member := range members {
+		buf = appendKey(buf, member.key, i == 0)
+		if !member.object {
+			buf = append(buf, member.raw...)
+			continue
+		}
+
+		buf = append(buf, '{')
+		for j, child := range member.members {
+			buf = appendKey(buf, child.key, j == 0)
+			buf = append(buf, child.raw...)
+		}
+		buf = append(buf, '}')
+	}
+	buf = notes.appendMarkers(buf, len(members) == 0)
+	return append(buf, '}')
+}
+
+// appendMarkers writes the Truncated and EncodingErrors keys, if anything was truncated or left out
+func (notes *jsonNotes) appendMarkers(buf []byte, first bool) []byte {
+	if len(notes.truncated) > 0 {
+		buf = appendKey(buf, Truncated, first)
+		buf = appendStrings(buf, notes.truncated)
+		first = false
+	}
+	if len(notes.errors) > 0 {
+		buf = appendKey(buf, EncodingErrors, first)
+		buf = appendStrings(buf, notes.errors)
+	}
+	return buf
+}
+
+// appendStrings writes a sorted JSON array of strings
+func appendStrings(buf []byte, values []string) []byte {
+	sorted := make([]string, len(values))
+	copy(sorted, values)
+	sort.Strings(sorted)
+
+	buf = append(buf, '[')
+	for i, value := range sorted {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendString(buf, value)
+	}
+	return append(buf, ']')
+}
@@ -0,0 +1,143 @@
+package log
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func Test_JSON_KeyOrder(t *testing.T) {
+	fields := Fields{
+		Properties: Fields{"b": 2, "a": 1},
+		Exception:  Fields{"type": "*errors.errorString", "error": "bad"},
+		"zebra":    "z",
+		"apple":    "a",
+		User:       "user",
+		Event:      "event",
+		Severity:   InfoSev,
+		Time:       "now",
+		TraceID:    "1-2-3",
+	}
+
+	str := JSONEncoder{}.Encode(fields)
+	assert.Equal(t, str, `{"time":"now","severity":"INFO","event":"event","trace_id":"1-2-3","user":"user",`+
+		`"apple":"a","zebra":"z","exception":{"error":"bad","type":"*errors.errorString"},"properties":{"a":1,"b":2}}`)
+}
+
+func Test_JSON_ValueLimit(t *testing.T) {
+	long := strings.Repeat("é", 20)
+
+	str := JSONEncoder{MaxValueLength: 10}.Encode(Fields{
+		Event:      "event",
+		Properties: Fields{"long": long, "short": "abc", "nested": Fields{"list": []string{"ok", long}}},
+	})
+
+	var entry map[string]interface{}
+	assert.NilError(t, json.Unmarshal([]byte(str), &entry), str)
+
+	properties := entry[Properties].(map[string]interface{})
+	assert.Equal(t, properties["long"], "ééé...") // 10 bytes at most, and never splits a rune
+	assert.Equal(t, properties["short"], "abc")
+	assert.DeepEqual(t, entry[Truncated], []interface{}{"properties.long", "properties.nested.list[1]"})
+}
+
+func Test_JSON_NoLimits(t *testing.T) {
+	long := strings.Repeat("a", DefaultMaxValueLength+1)
+
+	str := JSONEncoder{MaxValueLength: -1, MaxLineLength: -1}.Encode(Fields{Event: "event", Properties: Fields{"long": long}})
+	assert.Assert(t, strings.Contains(str, long))
+	assert.Assert(t, !strings.Contains(str, Truncated))
+}
+
+func Test_JSON_LineLimit(t *testing.T) {
+	fields := Fields{
+		Time:     "now",
+		Severity: ErrorSev,
+		Event:    "event",
+		Exception: Fields{
+			"error": "bad",
+			"type":  "*errors.errorString",
+			"trace": strings.Repeat("t", 300),
+		},
+		Properties: Fields{
+			"small":  "s",
+			"medium": strings.Repeat("m", 200),
+			"large":  strings.Repeat("l", 400),
+		},
+	}
+
+	str := JSONEncoder{MaxLineLength: 500}.Encode(fields)
+	assert.Assert(t, len(str) <= 500, len(str))
+
+	var entry map[string]interface{}
+	assert.NilError(t, json.Unmarshal([]byte(str), &entry), str)
+	assert.DeepEqual(t, entry[Truncated], []interface{}{"exception.trace", "properties.large"})
+	assert.Equal(t, entry[Exception].(map[string]interface{})["error"], "bad")
+	assert.Equal(t, entry[Properties].(map[string]interface{})["small"], "s")
+
+	// nothing left to drop, so just the time, severity and event
+	str = JSONEncoder{MaxLineLength: 10}.Encode(fields)
+	entry = map[string]interface{}{}
+	assert.NilError(t, json.Unmarshal([]byte(str), &entry), str)
+	assert.Equal(t, entry[Event], "event")
+	assert.Equal(t, entry[Severity], ErrorSev)
+	assert.Assert(t, entry[Properties] == nil)
+}
+
+func Test_JSON_EncodingErrors(t *testing.T) {
+	str := JSONEncoder{}.Encode(Fields{
+		Event: "event",
+		Properties: Fields{
+			"ratio":  math.NaN(),
+			"nested": map[string]interface{}{"func": func() {}},
+			"ok":     1,
+			"func":   func() {},
+		},
+		"nil": nil,
+	})
+
+	var entry map[string]interface{}
+	assert.NilError(t, json.Unmarshal([]byte(str), &entry), str)
+	assert.DeepEqual(t, entry[Properties], map[string]interface{}{"ok": float64(1)})
+	assert.DeepEqual(t, entry[EncodingErrors], []interface{}{
+		"properties.nested: json: unsupported type: func()",
+		"properties.ratio: json: unsupported value: NaN",
+	})
+	assert.Assert(t, strings.Contains(str, `"nil":null`), str)
+}
+
+func Test_JSON_TypedSameAsFields(t *testing.T) {
+	long := strings.Repeat("x", DefaultMaxValueLength+10)
+
+	fieldsBuffer, fieldsLogger := newBufferLogger()
+	typedBuffer, typedLogger := newBufferLogger()
+
+	fieldsLogger.Info("event", Fields{"long": long, "nan": math.NaN(), "object": Fields{"inner": long}})
+	typedLogger.InfoWith("event", String("long", long), Float64("nan", math.NaN()), Object("object", Fields{"inner": long}))
+
+	expected := timeValue.ReplaceAllString(fieldsBuffer.String(), `"time":""`)
+	actual := timeValue.ReplaceAllString(typedBuffer.String(), `"time":""`)
+	assert.Equal(t, actual, expected)
+	assert.Assert(t, strings.Contains(actual, `"_truncated":["properties.long","properties.object.inner"]`), actual)
+	assert.Assert(t, strings.Contains(actual, `"_encoding_errors":["properties.nan: json: unsupported value: NaN"]`), actual)
+}
+
+func Test_JSON_TypedLineLimit(t *testing.T) {
+	memBuffer, logger := newBufferLogger()
+
+	fields := make([]Field, 0, 20)
+	for i := 0; i < 20; i++ {
+		fields = append(fields, String(strings.Repeat("k", i+1), strings.Repeat("v", DefaultMaxValueLength)))
+	}
+	logger.InfoWith("event", fields...)
+
+	line := strings.TrimSpace(memBuffer.String())
+	assert.Assert(t, len(line) <= DefaultMaxLineLength, len(line))
+
+	var entry map[string]interface{}
+	assert.NilError(t, json.Unmarshal([]byte(line), &entry))
+	assert.Assert(t, len(entry[Truncated].([]interface{})) > 0)
+}
@@ -0,0 +1,169 @@
+package log
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// lineWriter is implemented by writers that can take an entry already encoded as a JSON line, so typed Field
+// entries never have to be turned into Fields
+type lineWriter interface {
+	jsonEncoder() (JSONEncoder, bool)
+	writeLine(line []byte)
+}
+
+// maxPooledLine stops the odd huge entry from keeping a huge buffer in the pool forever
+const maxPooledLine = 64 * 1024
+
+var linePool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 1024)
+		return &buf
+	},
+}
+
+func getLineBuffer() *[]byte {
+	return linePool.Get().(*[]byte)
+}
+
+func putLineBuffer(buf *[]byte) {
+	if cap(*buf) > maxPooledLine {
+		return
+	}
+	linePool.Put(buf)
+}
+
+func appendKey(buf []byte, key string, first bool) []byte {
+	if !first {
+		buf = append(buf, ',')
+	}
+	buf = appendString(buf, key)
+	return append(buf, ':')
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendString quotes and escapes s exactly as encoding/json does (including the HTML characters)
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				buf = append(buf, '\\', c)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
+
+func appendInt(buf []byte, value int64) []byte {
+	return strconv.AppendInt(buf, value, 10)
+}
+
+func appendBool(buf []byte, value bool) []byte {
+	return strconv.AppendBool(buf, value)
+}
+
+// appendFloat writes value the way encoding/json does, and returns false for NaN and Inf as they aren't valid JSON
+func appendFloat(buf []byte, value float64) ([]byte, bool) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return buf, false
+	}
+
+	format := byte('f')
+	if abs := math.Abs(value); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	buf = strconv.AppendFloat(buf, value, format, -1, 64)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(buf)
+		if n >= 4 && buf[n-4] == 'e' && buf[n-3] == '-' && buf[n-2] == '0' {
+			buf[n-2] = buf[n-1]
+			buf = buf[:n-1]
+		}
+	}
+	return buf, true
+}
+
+// appendShortestFloat writes value the same as fmt's %g verb, see DurationAsISO8601
+func appendShortestFloat(buf []byte, value float64) []byte {
+	return strconv.AppendFloat(buf, value, 'g', -1, 64)
+}
+
+// unsupportedFloat is the error encoding/json gives for NaN and Inf
+func unsupportedFloat(value float64) error {
+	return &json.UnsupportedValueError{Value: reflect.ValueOf(value), Str: strconv.FormatFloat(value, 'g', -1, 64)}
+}
+
+// appendValue writes any value as JSON, and returns the error if it can't be serialized
+func appendValue(buf []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(buf, "null"...), nil
+	case string:
+		return appendString(buf, v), nil
+	case bool:
+		return appendBool(buf, v), nil
+	case int:
+		return appendInt(buf, int64(v)), nil
+	case int32:
+		return appendInt(buf, int64(v)), nil
+	case int64:
+		return appendInt(buf, v), nil
+	case float64:
+		if buf, ok := appendFloat(buf, v); ok {
+			return buf, nil
+		}
+		return buf, unsupportedFloat(v)
+	case Fields:
+		value = v.ToSnakeCase()
+	}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return buf, err
+	}
+	return append(buf, bytes...), nil
+}
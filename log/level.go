@@ -1,21 +1,40 @@
 package log
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cultureamp/glamplify/helper"
+)
 
 type systemLogLevel struct {
-	sysLogLevel int
+	sysLogLevel int32
 	lookup      map[string]int
+
+	mutex  sync.RWMutex
+	events map[string]int
+}
+
+// loggerLevel is shared between copies of a Logger so that an override set on one applies to all of them
+type loggerLevel struct {
+	level int32
+}
+
+const noLevel = -1
+
+var severityTable = map[string]int{
+	DebugSev: 0,
+	InfoSev:  1,
+	WarnSev:  2,
+	ErrorSev: 3,
+	FatalSev: 4,
 }
 
 func newSystemLogLevel() *systemLogLevel {
 
-	table := map[string]int {
-		DebugSev: 0,
-		InfoSev: 1,
-		WarnSev: 2,
-		ErrorSev: 3,
-		FatalSev: 4,
-	}
+	table := severityTable
 
 	level, ok := os.LookupEnv("LOG_LEVEL")
 	if !ok {
@@ -27,21 +46,170 @@ func newSystemLogLevel() *systemLogLevel {
 	}
 
 	return &systemLogLevel{
-		sysLogLevel: logLevel,
-		lookup: table,
+		sysLogLevel: int32(logLevel),
+		lookup:      table,
+		events:      map[string]int{},
 	}
 }
 
-func (sev systemLogLevel) shouldLog(severity string) bool {
+func newLoggerLevel() *loggerLevel {
+	return &loggerLevel{level: noLevel}
+}
+
+// SetLevel changes the minimum severity logged by every Logger, eg. SetLevel(log.WarnSev)
+func SetLevel(severity string) error {
+	return sevLevel.setLevel(severity)
+}
+
+// GetLevel returns the current minimum severity logged by every Logger
+func GetLevel() string {
+	return sevLevel.getLevel()
+}
+
+// SetEventLevel overrides the minimum severity for a single event name, regardless of the global or Logger level
+func SetEventLevel(event string, severity string) error {
+	return sevLevel.setEventLevel(event, severity)
+}
+
+// ClearEventLevel removes the override for an event name set by SetEventLevel
+func ClearEventLevel(event string) {
+	sevLevel.clearEventLevel(event)
+}
+
+// GetEventLevels returns all of the event name overrides
+func GetEventLevels() map[string]string {
+	return sevLevel.getEventLevels()
+}
+
+func (sev *systemLogLevel) shouldLog(severity string) bool {
 
 	level, ok := sev.lookup[severity]
 	if !ok {
 		return false
 	}
 
-	if level >= sev.sysLogLevel {
+	if level >= int(atomic.LoadInt32(&sev.sysLogLevel)) {
 		return true
 	}
 
 	return false
 }
+
+// shouldLogEvent checks the event override first, then the logger override, then the global level
+func (sev *systemLogLevel) shouldLogEvent(event string, severity string, override *loggerLevel) bool {
+
+	level, ok := sev.lookup[severity]
+	if !ok {
+		return false
+	}
+
+	sev.mutex.RLock()
+	eventLevel, found := sev.events[event]
+	sev.mutex.RUnlock()
+	if found {
+		return level >= eventLevel
+	}
+
+	if override != nil {
+		if loggerLevel := atomic.LoadInt32(&override.level); loggerLevel != noLevel {
+			return level >= int(loggerLevel)
+		}
+	}
+
+	return sev.shouldLog(severity)
+}
+
+func (sev *systemLogLevel) setLevel(severity string) error {
+	level, err := sev.parse(severity)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreInt32(&sev.sysLogLevel, int32(level))
+	return nil
+}
+
+func (sev *systemLogLevel) getLevel() string {
+	return sev.name(int(atomic.LoadInt32(&sev.sysLogLevel)))
+}
+
+func (sev *systemLogLevel) setEventLevel(event string, severity string) error {
+	level, err := sev.parse(severity)
+	if err != nil {
+		return err
+	}
+
+	sev.mutex.Lock()
+	defer sev.mutex.Unlock()
+
+	sev.events[helper.ToSnakeCase(event)] = level
+	return nil
+}
+
+func (sev *systemLogLevel) clearEventLevel(event string) {
+	sev.mutex.Lock()
+	defer sev.mutex.Unlock()
+
+	delete(sev.events, helper.ToSnakeCase(event))
+}
+
+func (sev *systemLogLevel) getEventLevels() map[string]string {
+	sev.mutex.RLock()
+	defer sev.mutex.RUnlock()
+
+	levels := make(map[string]string, len(sev.events))
+	for event, level := range sev.events {
+		levels[event] = sev.name(level)
+	}
+	return levels
+}
+
+func (sev *systemLogLevel) replaceEventLevels(levels map[string]string) error {
+	events := make(map[string]int, len(levels))
+	for event, severity := range levels {
+		level, err := sev.parse(severity)
+		if err != nil {
+			return err
+		}
+		events[helper.ToSnakeCase(event)] = level
+	}
+
+	sev.mutex.Lock()
+	defer sev.mutex.Unlock()
+
+	sev.events = events
+	return nil
+}
+
+func (sev *systemLogLevel) parse(severity string) (int, error) {
+	level, ok := sev.lookup[severity]
+	if !ok {
+		return noLevel, fmt.Errorf("unknown severity '%s', must be one of %s, %s, %s, %s or %s", severity, DebugSev, InfoSev, WarnSev, ErrorSev, FatalSev)
+	}
+	return level, nil
+}
+
+func (sev *systemLogLevel) name(level int) string {
+	for name, l := range sev.lookup {
+		if l == level {
+			return name
+		}
+	}
+	return Unknown
+}
+
+// severityAtLeast returns true if severity is the same or more severe than min. An unknown min lets everything through.
+func severityAtLeast(severity string, min string) bool {
+
+	minLevel, ok := severityTable[min]
+	if !ok {
+		return true
+	}
+
+	level, ok := severityTable[severity]
+	if !ok {
+		return false
+	}
+
+	return level >= minLevel
+}
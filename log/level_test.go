@@ -1,8 +1,13 @@
 package log
 
 import (
+	"bytes"
+	"encoding/json"
 	"gotest.tools/assert"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -66,4 +71,121 @@ func Test_Sev_Log_Env_Unknown(t *testing.T) {
 	assert.Assert(t, ok, ok)
 	ok = sev.shouldLog(FatalSev)
 	assert.Assert(t, ok, ok)
-}
\ No newline at end of file
+}
+func Test_SetLevel(t *testing.T) {
+	defer SetLevel(DebugSev)
+
+	err := SetLevel(WarnSev)
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, GetLevel() == WarnSev, GetLevel())
+
+	memBuffer, logger := newBufferLogger()
+	logger.Info("info_event")
+	logger.Warn("warn_event")
+
+	msg := memBuffer.String()
+	assert.Assert(t, !strings.Contains(msg, "info_event"), msg)
+	assertContainsString(t, msg, "event", "warn_event")
+
+	err = SetLevel("unknown")
+	assert.Assert(t, err != nil, err)
+	assert.Assert(t, GetLevel() == WarnSev, GetLevel())
+}
+
+func Test_SetEventLevel(t *testing.T) {
+	defer SetLevel(DebugSev)
+	defer ClearEventLevel("noisy_event")
+
+	SetLevel(ErrorSev)
+	err := SetEventLevel("noisyEvent", DebugSev)
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, GetEventLevels()["noisy_event"] == DebugSev, GetEventLevels())
+
+	memBuffer, logger := newBufferLogger()
+	logger.Debug("noisy_event")
+	logger.Debug("other_event")
+
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "event", "noisy_event")
+	assert.Assert(t, !strings.Contains(msg, "other_event"), msg)
+
+	ClearEventLevel("noisy_event")
+	memBuffer.Reset()
+	logger.Debug("noisy_event")
+	assert.Assert(t, memBuffer.Len() == 0, memBuffer.String())
+}
+
+func Test_Logger_SetLevel(t *testing.T) {
+	defer SetLevel(DebugSev)
+
+	SetLevel(ErrorSev)
+
+	memBuffer, logger := newBufferLogger()
+	_, other := newBufferLogger()
+
+	err := logger.SetLevel(DebugSev)
+	assert.Assert(t, err == nil, err)
+
+	// copies share the override
+	logger.Event("segment_event").Debug("message")
+	logger.Debug("debug_event")
+	other.Debug("other_event")
+
+	msg := memBuffer.String()
+	assertContainsString(t, msg, "event", "segment_event")
+	assertContainsString(t, msg, "event", "debug_event")
+
+	logger.ClearLevel()
+	memBuffer.Reset()
+	logger.Debug("debug_event")
+	assert.Assert(t, memBuffer.Len() == 0, memBuffer.String())
+
+	err = logger.SetLevel("unknown")
+	assert.Assert(t, err != nil, err)
+}
+
+func Test_LevelHandler(t *testing.T) {
+	defer SetLevel(DebugSev)
+	defer sevLevel.replaceEventLevels(map[string]string{})
+
+	handler := LevelHandler()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"WARN","events":{"request_handled":"DEBUG"}}`))
+	handler.ServeHTTP(rr, req)
+	assert.Assert(t, rr.Code == http.StatusOK, rr.Code)
+	assert.Assert(t, GetLevel() == WarnSev, GetLevel())
+	assert.Assert(t, GetEventLevels()["request_handled"] == DebugSev, GetEventLevels())
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/log/level", nil)
+	handler.ServeHTTP(rr, req)
+	assert.Assert(t, rr.Code == http.StatusOK, rr.Code)
+
+	var settings LevelSettings
+	err := json.Unmarshal(rr.Body.Bytes(), &settings)
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, settings.Level == WarnSev, settings)
+	assert.Assert(t, settings.Events["request_handled"] == DebugSev, settings)
+
+	// invalid changes are rejected as a whole
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"LOUD","events":{}}`))
+	handler.ServeHTTP(rr, req)
+	assert.Assert(t, rr.Code == http.StatusBadRequest, rr.Code)
+	assert.Assert(t, GetLevel() == WarnSev, GetLevel())
+	assert.Assert(t, len(GetEventLevels()) == 1, GetEventLevels())
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/log/level", nil)
+	handler.ServeHTTP(rr, req)
+	assert.Assert(t, rr.Code == http.StatusMethodNotAllowed, rr.Code)
+}
+
+func newBufferLogger() (*bytes.Buffer, *Logger) {
+	memBuffer := &bytes.Buffer{}
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = memBuffer
+	})
+	return memBuffer, NewWitCustomWriter(rsFields, writer)
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// LevelSettings is the body returned by GET and accepted by PUT on the LevelHandler
+type LevelSettings struct {
+	Level  string            `json:"level,omitempty"`
+	Events map[string]string `json:"events,omitempty"`
+}
+
+// LevelHandler returns a http.Handler that lets operators inspect (GET) and change (PUT) the global level and
+// the per event overrides at runtime, eg.
+//
+//   PUT {"level": "INFO", "events": {"request_handled": "DEBUG"}}
+//
+// A PUT without "level" leaves the global level unchanged, a PUT without "events" leaves the event overrides unchanged,
+// and a PUT with "events": {} removes them all. Protect this handler, it is not meant to be public.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeLevelSettings(w)
+
+		case http.MethodPut:
+			var settings struct {
+				Level  string             `json:"level"`
+				Events *map[string]string `json:"events"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			// validate everything before changing anything
+			if settings.Level != "" {
+				if _, err := sevLevel.parse(settings.Level); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if settings.Events != nil {
+				if err := sevLevel.replaceEventLevels(*settings.Events); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if settings.Level != "" {
+				sevLevel.setLevel(settings.Level)
+			}
+
+			writeLevelSettings(w)
+
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeLevelSettings(w http.ResponseWriter) {
+	settings := LevelSettings{
+		Level:  GetLevel(),
+		Events: GetEventLevels(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settings)
+}
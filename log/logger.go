@@ -3,6 +3,7 @@ package log
 import (
 	"context"
 	"net/http"
+	"sync/atomic"
 
 	gcontext "github.com/cultureamp/glamplify/context"
 	"github.com/cultureamp/glamplify/helper"
@@ -14,6 +15,11 @@ type Logger struct {
 	fields    Fields
 	sysValues *SystemValues
 	writer    Writer
+	level     *loggerLevel
+	buffer    *debugBuffer
+	hooks     []Hook
+
+	callerSkip int
 }
 
 var (
@@ -34,16 +40,22 @@ func NewWitCustomWriter(rsFields gcontext.RequestScopedFields, writer Writer, fi
 
 // NewFromCtx creates a new logger from a context, which should contain RequestScopedFields.
 // If the context does not contain then, then this method will NOT add them in.
+// If the context was created with AddDebugBuffer, entries below the log level are held until an ERROR or FATAL.
 func NewFromCtx(ctx context.Context, fields ...Fields) *Logger {
 	rsFields, _ := gcontext.GetRequestScopedFields(ctx)
-	return New(rsFields, fields...)
+	logger := New(rsFields, fields...)
+	logger.buffer = debugBufferFromCtx(ctx)
+	return logger
 }
 
 // NewFromCtxWithCustomerWriter creates a new logger from a context, which should contain RequestScopedFields.
 // If the context does not contain then, then this method will NOT add them in.
+// If the context was created with AddDebugBuffer, entries below the log level are held until an ERROR or FATAL.
 func NewFromCtxWithCustomerWriter(ctx context.Context, writer Writer, fields ...Fields) *Logger {
 	rsFields, _ := gcontext.GetRequestScopedFields(ctx)
-	return NewWitCustomWriter(rsFields, writer, fields...)
+	logger := NewWitCustomWriter(rsFields, writer, fields...)
+	logger.buffer = debugBufferFromCtx(ctx)
+	return logger
 }
 
 // NewFromRequest creates a new logger from a http.Request, which should contain RequestScopedFields.
@@ -68,6 +80,7 @@ func newLogger(rsFields gcontext.RequestScopedFields, writer Writer, fields ...F
 		rsFields: rsFields,
 		writer:   writer,
 		fields:   merged,
+		level:    newLoggerLevel(),
 	}
 	logger.sysValues = df
 	return logger
@@ -132,30 +145,50 @@ func (logger Logger) Error(event string, err error, fields ...Fields) {
 }
 
 // Fatal writes a error message with optional types to the underlying standard writer and then calls panic!
-// Panic will terminate the current go routine.
+// Panic will terminate the current go routine. See SetFatalPolicy to exit (after running the shutdown hooks) instead.
 // Useful to trace catastrophic errors that are not recoverable. These should always be logged.
 // Use snake_case keys and lower case values if possible.
 func Fatal(rsFields gcontext.RequestScopedFields, event string, err error, fields ...Fields) {
 	event = defaultLogger.write(rsFields, event, err, FatalSev, fields...)
 
-	// time to panic!
-	panic(event)
+	fatal(event, err)
 }
 
 // Fatal writes a error message with optional types to the underlying standard writer and then calls panic!
-// Panic will terminate the current go routine.
+// Panic will terminate the current go routine. See SetFatalPolicy to exit (after running the shutdown hooks) instead.
 // Useful to trace catastrophic errors that are not recoverable. These should always be logged.
 // Use snake_case keys and lower case values if possible.
 func (logger Logger) Fatal(event string, err error, fields ...Fields) {
 	event = logger.write(logger.rsFields, event, err, FatalSev, fields...)
 
-	// time to panic!
-	panic(event)
+	fatal(event, err)
+}
+
+// SetLevel overrides the global minimum severity for this Logger (and any copies of it), eg. logger.SetLevel(log.DebugSev)
+func (logger Logger) SetLevel(severity string) error {
+	level, err := sevLevel.parse(severity)
+	if err != nil {
+		return err
+	}
+	if logger.level != nil {
+		atomic.StoreInt32(&logger.level.level, int32(level))
+	}
+	return nil
+}
+
+// ClearLevel removes the override set by SetLevel so this Logger uses the global level again
+func (logger Logger) ClearLevel() {
+	if logger.level != nil {
+		atomic.StoreInt32(&logger.level.level, noLevel)
+	}
 }
 
 // Event method uses expressive syntax format: logger.Event("event_name").Fields(fields...).Info("message")
 func (logger Logger) Event(event string) *Segment {
 
+	// the Segment methods add a frame between the caller and the Logger
+	logger.callerSkip++
+
 	return &Segment{
 		logger: logger,
 		event: event,
@@ -166,17 +199,71 @@ func (logger Logger) Event(event string) *Segment {
 func (logger Logger) write(rsFields gcontext.RequestScopedFields, event string, err error, sev string, fields ...Fields) string {
 	event = helper.ToSnakeCase(event)
 
-	if sevLevel.shouldLog(sev) {
-		system := logger.sysValues.getSystemValues(rsFields, event, sev)
-		if err != nil {
-			system = logger.sysValues.getErrorValues(err, system)
+	if !sevLevel.shouldLogEvent(event, sev, logger.level) {
+		if logger.buffer != nil {
+			system, properties := logger.entry(rsFields, event, err, sev, fields...)
+			logger.buffer.add(logger, sev, event, system, properties)
 		}
+		return event
+	}
 
-		properties := logger.fields.Merge(fields...)
-		logger.writer.WriteFields(system, properties)
+	if !GetSampler().sample(event, sev) {
+		return event
+	}
+
+	system, properties := logger.entry(rsFields, event, err, sev, fields...)
+
+	schema := GetSchema()
+	violations := schema.Validate(properties)
+	if len(violations) > 0 {
+		properties = schema.removeReserved(properties)
+	}
+
+	logger.writeEntry(sev, event, system, properties)
+
+	if len(violations) > 0 {
+		logger.writeSchemaViolation(rsFields, event, violations)
 	}
 
 	return event
 }
 
+// writeEntry runs the hooks (which can veto the entry) and then writes it. An ERROR or FATAL writes the entries held
+// by the debug buffer first, each through the hooks and writer of the Logger that held it.
+func (logger Logger) writeEntry(sev string, event string, system Fields, properties Fields) {
+	if logger.hasHooks() && !logger.fireHooks(sev, event, system, properties) {
+		return
+	}
+	if logger.buffer != nil && severityAtLeast(sev, ErrorSev) {
+		logger.buffer.flush()
+	}
+	logger.writer.WriteFields(system, properties)
+}
+
+func (logger Logger) entry(rsFields gcontext.RequestScopedFields, event string, err error, sev string, fields ...Fields) (Fields, Fields) {
+	system := logger.sysValues.getSystemValues(rsFields, event, sev, entrySkip+logger.callerSkip)
+	if err != nil {
+		system = logger.sysValues.getErrorValues(err, system)
+	}
+
+	properties := logger.fields.Merge(fields...)
+	properties = GetRedactor().Redact(properties)
 
+	return system, properties
+}
+
+func (logger Logger) writeSamplingSummary(suppressed map[string]int) {
+	counts := Fields{}
+	for event, count := range suppressed {
+		counts[event] = count
+	}
+
+	system := logger.sysValues.getSystemValues(logger.rsFields, SamplingSummaryEvent, InfoSev, entrySkip+logger.callerSkip)
+	logger.writer.WriteFields(system, Fields{Suppressed: counts})
+}
+
+func (logger Logger) writeSchemaViolation(rsFields gcontext.RequestScopedFields, event string, violations []string) {
+	// writeSchemaViolation <- write <- Logger.Info <- your code
+	system := logger.sysValues.getSystemValues(rsFields, SchemaViolationEvent, WarnSev, entrySkip+logger.callerSkip)
+	logger.writer.WriteFields(system, Fields{ViolatingEvent: event, Violations: violations})
+}
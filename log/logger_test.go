@@ -820,11 +820,48 @@ func BenchmarkLogging(b *testing.B) {
 		"string3 space": "world",
 	}
 
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		logger.Info("test details", fields)
 	}
 }
 
+func BenchmarkLogging_TypedFields(b *testing.B) {
+	writer := NewWriter(func(conf *WriterConfig) {
+		conf.Output = ioutil.Discard
+	})
+	logger := newLogger(rsFields, writer)
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		logger.InfoWith("test details",
+			String("string", "hello"),
+			Int("int", 123),
+			Float64("float", 42.48),
+			String("string2", "hello world"),
+			String("string3 space", "world"),
+		)
+	}
+}
+
+func BenchmarkLogging_TypedFields_Async(b *testing.B) {
+	writer := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = ioutil.Discard
+		conf.Overflow = OverflowDropNewest
+	})
+	defer writer.Close(context.Background())
+	logger := newLogger(rsFields, writer)
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		logger.InfoWith("test details",
+			String("string", "hello"),
+			Int("int", 123),
+			Duration("duration", time.Second),
+		)
+	}
+}
+
 func assertContainsString(t *testing.T, log string, key string, val string) {
 	// Check that the keys and values are in the log line
 	find := fmt.Sprintf("\"%s\":\"%s\"", key, val)
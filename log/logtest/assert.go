@@ -0,0 +1,102 @@
+package logtest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cultureamp/glamplify/log"
+)
+
+var severities = []string{log.DebugSev, log.InfoSev, log.WarnSev, log.ErrorSev, log.FatalSev}
+
+// AssertLogged fails the test unless the event was logged, and returns the latest entry for it
+func (recorder *Recorder) AssertLogged(t testing.TB, event string) Entry {
+	t.Helper()
+
+	found := recorder.FindEvent(event)
+	if len(found) == 0 {
+		t.Fatalf("expected event '%s' to be logged, but it was not. logged: %v", event, recorder.events())
+		return Entry{}
+	}
+	return found[len(found)-1]
+}
+
+// AssertNotLogged fails the test if the event was logged
+func (recorder *Recorder) AssertNotLogged(t testing.TB, event string) {
+	t.Helper()
+
+	if found := recorder.FindEvent(event); len(found) > 0 {
+		t.Errorf("expected event '%s' not to be logged, but it was %d time(s)", event, len(found))
+	}
+}
+
+// AssertCount fails the test unless the event was logged exactly count times
+func (recorder *Recorder) AssertCount(t testing.TB, event string, count int) {
+	t.Helper()
+
+	if found := recorder.FindEvent(event); len(found) != count {
+		t.Errorf("expected event '%s' to be logged %d time(s), but it was %d time(s)", event, count, len(found))
+	}
+}
+
+// AssertNothingAbove fails the test if anything more severe than severity was logged,
+// eg. AssertNothingAbove(t, log.InfoSev) fails on any WARN, ERROR or FATAL
+func (recorder *Recorder) AssertNothingAbove(t testing.TB, severity string) {
+	t.Helper()
+
+	max := severityIndex(severity)
+	for _, entry := range recorder.Entries() {
+		if severityIndex(entry.Severity) > max {
+			t.Errorf("expected nothing above %s to be logged, but '%s' was logged at %s", severity, entry.Event, entry.Severity)
+		}
+	}
+}
+
+// AssertField fails the test unless the value at path (see Entry.Field) equals expected. Expected is put through json
+// first, so AssertField(t, entry, "properties.count", 1) passes even though the entry has 1.0.
+func AssertField(t testing.TB, entry Entry, path string, expected interface{}) {
+	t.Helper()
+
+	if entry.Err != nil {
+		t.Errorf("expected '%s' in event '%s', but the entry couldn't be read back as json: %v. line: %s", path, entry.Event, entry.Err, entry.Raw)
+		return
+	}
+	actual, ok := entry.Field(path)
+	if !ok {
+		t.Errorf("expected '%s' in event '%s', but it was missing. fields: %v", path, entry.Event, entry.Fields)
+		return
+	}
+	if !reflect.DeepEqual(actual, normalize(expected)) {
+		t.Errorf("expected '%s' to be %v in event '%s', but it was %v", path, expected, entry.Event, actual)
+	}
+}
+
+// AssertNoField fails the test if there is a value at path (see Entry.Field)
+func AssertNoField(t testing.TB, entry Entry, path string) {
+	t.Helper()
+
+	if entry.Err != nil {
+		t.Errorf("expected no '%s' in event '%s', but the entry couldn't be read back as json: %v. line: %s", path, entry.Event, entry.Err, entry.Raw)
+		return
+	}
+	if actual, ok := entry.Field(path); ok {
+		t.Errorf("expected no '%s' in event '%s', but it was %v", path, entry.Event, actual)
+	}
+}
+
+func (recorder *Recorder) events() []string {
+	var events []string
+	for _, entry := range recorder.Entries() {
+		events = append(events, entry.Event)
+	}
+	return events
+}
+
+func severityIndex(severity string) int {
+	for i, sev := range severities {
+		if sev == severity {
+			return i
+		}
+	}
+	return -1
+}
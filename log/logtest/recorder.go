@@ -0,0 +1,173 @@
+// Package logtest records log entries in memory so tests can assert on what was logged, instead of checking strings
+// in a bytes.Buffer.
+//
+//	recorder := logtest.NewRecorder()
+//	logger := log.NewWitCustomWriter(rsFields, recorder)
+//	...
+//	entry := recorder.AssertLogged(t, "user_created")
+//	logtest.AssertField(t, entry, "properties.user.id", 123)
+//	recorder.AssertNothingAbove(t, log.InfoSev)
+package logtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/helper"
+	"github.com/cultureamp/glamplify/log"
+)
+
+// Entry is a log entry as it would have been written, so values have been through json (eg. numbers are float64 and
+// structs are maps). Use Field to get a value by its path.
+type Entry struct {
+	Time     string
+	Severity string
+	Event    string
+
+	// Fields is the whole entry, with the properties under "properties"
+	Fields map[string]interface{}
+
+	// Err is set when the written line couldn't be read back as json, and Raw is then the line itself
+	Err error
+	Raw string
+}
+
+// Recorder is a log.Writer that keeps every entry in memory. It is safe for concurrent use.
+type Recorder struct {
+	mutex   sync.Mutex
+	entries []Entry
+}
+
+// NewRecorder creates a new, empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// NewLogger creates a log.Logger that writes to a new Recorder
+func NewLogger(rsFields gcontext.RequestScopedFields, fields ...log.Fields) (*log.Logger, *Recorder) {
+	recorder := NewRecorder()
+	return log.NewWitCustomWriter(rsFields, recorder, fields...), recorder
+}
+
+func (recorder *Recorder) WriteFields(system log.Fields, fields ...log.Fields) {
+	entry := system.Merge()
+	properties := log.Fields{}.Merge(fields...)
+	if len(properties) > 0 {
+		entry[log.Properties] = properties
+	}
+
+	// go through json so we see exactly what would have been written, without any values truncated or left out
+	encoder := log.JSONEncoder{MaxValueLength: -1, MaxLineLength: -1}
+	recorder.record(system, encoder.Encode(entry.ToSnakeCase()))
+}
+
+func (recorder *Recorder) record(system log.Fields, line string) {
+	parsed := map[string]interface{}{}
+	recorded := Entry{Fields: parsed}
+	if err := json.Unmarshal([]byte(line), &parsed); err != nil {
+		// keep it, so the test can see what went wrong instead of the entry just being missing
+		recorded = Entry{
+			Time:     stringValue(system[log.Time]),
+			Severity: stringValue(system[log.Severity]),
+			Event:    stringValue(system[log.Event]),
+			Err:      err,
+			Raw:      line,
+		}
+	} else {
+		recorded.Time = stringValue(parsed[log.Time])
+		recorded.Severity = stringValue(parsed[log.Severity])
+		recorded.Event = stringValue(parsed[log.Event])
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.entries = append(recorder.entries, recorded)
+}
+
+// Entries returns a copy of every entry recorded, oldest first
+func (recorder *Recorder) Entries() []Entry {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	entries := make([]Entry, len(recorder.entries))
+	copy(entries, recorder.entries)
+	return entries
+}
+
+// Reset forgets every entry recorded so far, eg. between sub tests
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.entries = nil
+}
+
+// Filter returns the entries that match, oldest first
+func (recorder *Recorder) Filter(match func(entry Entry) bool) []Entry {
+	var found []Entry
+	for _, entry := range recorder.Entries() {
+		if match(entry) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// FindEvent returns the entries for an event, oldest first. The event is converted to snake_case as the Logger does.
+func (recorder *Recorder) FindEvent(event string) []Entry {
+	event = helper.ToSnakeCase(event)
+	return recorder.Filter(func(entry Entry) bool {
+		return entry.Event == event
+	})
+}
+
+// FindSeverity returns the entries with a severity, oldest first
+func (recorder *Recorder) FindSeverity(severity string) []Entry {
+	return recorder.Filter(func(entry Entry) bool {
+		return entry.Severity == severity
+	})
+}
+
+// Field returns the value at a '.' separated path, eg. "trace_id", "properties.user.id" or "exception.error"
+func (entry Entry) Field(path string) (interface{}, bool) {
+	var value interface{} = entry.Fields
+	for _, key := range strings.Split(path, ".") {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = fields[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Property returns the value at a '.' separated path under "properties", eg. "user.id"
+func (entry Entry) Property(path string) (interface{}, bool) {
+	return entry.Field(log.Properties + "." + path)
+}
+
+// normalize puts a value through json the same way entries are, so 123 equals 123.0 and structs equal maps
+func normalize(value interface{}) interface{} {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	if err := decoder.Decode(&normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func stringValue(value interface{}) string {
+	str, _ := value.(string)
+	return str
+}
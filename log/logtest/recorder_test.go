@@ -0,0 +1,171 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/log"
+	"github.com/cultureamp/glamplify/monitor"
+	"gotest.tools/assert"
+)
+
+var rsFields = gcontext.RequestScopedFields{
+	TraceID:   "1-2-3",
+	RequestID: "7-8-9",
+}
+
+// fakeT records failures instead of failing the real test
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func Test_Recorder_Entries(t *testing.T) {
+	logger, recorder := NewLogger(rsFields)
+
+	logger.Info("user created", log.Fields{"user": user{ID: 123, Name: "bob"}, "count": 2})
+	logger.InfoWith("typed_event", log.String("string", "hello"))
+	logger.Error("request_failed", errors.New("it broke"))
+
+	entries := recorder.Entries()
+	assert.Assert(t, len(entries) == 3, entries)
+	assert.Equal(t, entries[0].Event, "user_created")
+	assert.Equal(t, entries[0].Severity, log.InfoSev)
+	assert.Assert(t, entries[0].Time != "")
+
+	entry := recorder.AssertLogged(t, "userCreated")
+	AssertField(t, entry, "trace_id", "1-2-3")
+	AssertField(t, entry, "properties.user.id", 123)
+	AssertField(t, entry, "properties.user", user{ID: 123, Name: "bob"})
+	AssertField(t, entry, "properties.count", 2)
+	AssertNoField(t, entry, "properties.missing")
+
+	value, ok := entry.Property("user.name")
+	assert.Assert(t, ok)
+	assert.Equal(t, value, "bob")
+
+	AssertField(t, recorder.AssertLogged(t, "typed_event"), "properties.string", "hello")
+	AssertField(t, recorder.AssertLogged(t, "request_failed"), "exception.error", "it broke")
+
+	assert.Assert(t, len(recorder.FindSeverity(log.ErrorSev)) == 1)
+	recorder.AssertCount(t, "user_created", 1)
+	recorder.AssertNotLogged(t, "never_logged")
+	recorder.AssertNothingAbove(t, log.ErrorSev)
+}
+
+func Test_Recorder_Failures(t *testing.T) {
+	logger, recorder := NewLogger(rsFields)
+	logger.Warn("warn_event", log.Fields{"count": 1})
+
+	fake := &fakeT{}
+	recorder.AssertLogged(fake, "missing_event")
+	recorder.AssertNotLogged(fake, "warn_event")
+	recorder.AssertCount(fake, "warn_event", 2)
+	recorder.AssertNothingAbove(fake, log.InfoSev)
+
+	entry := recorder.AssertLogged(t, "warn_event")
+	AssertField(fake, entry, "properties.count", 2)
+	AssertField(fake, entry, "properties.count.nested", 1)
+	AssertNoField(fake, entry, "properties.count")
+
+	assert.Assert(t, len(fake.failures) == 7, fake.failures)
+}
+
+func Test_Recorder_NotTruncated(t *testing.T) {
+	logger, recorder := NewLogger(rsFields)
+
+	long := strings.Repeat("a", 20*1024)
+	logger.Info("long_event", log.Fields{"long": long})
+
+	AssertField(t, recorder.AssertLogged(t, "long_event"), "properties.long", long)
+}
+
+func Test_Recorder_BadLine(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.record(log.Fields{log.Severity: log.InfoSev, log.Event: "bad_event"}, `{"event":`)
+
+	entry := recorder.AssertLogged(t, "bad_event")
+	assert.Equal(t, entry.Severity, log.InfoSev)
+	assert.Equal(t, entry.Raw, `{"event":`)
+	assert.Assert(t, entry.Err != nil)
+
+	fake := &fakeT{}
+	AssertField(fake, entry, "event", "bad_event")
+	AssertNoField(fake, entry, "properties.missing")
+	assert.Assert(t, len(fake.failures) == 2, fake.failures)
+}
+
+func Test_Recorder_Reset(t *testing.T) {
+	logger, recorder := NewLogger(rsFields)
+
+	t.Run("first", func(t *testing.T) {
+		defer recorder.Reset()
+		logger.Error("first_event", errors.New("bad"))
+		recorder.AssertLogged(t, "first_event")
+	})
+
+	t.Run("second", func(t *testing.T) {
+		defer recorder.Reset()
+		logger.Info("second_event")
+		recorder.AssertNotLogged(t, "first_event")
+		recorder.AssertNothingAbove(t, log.InfoSev)
+	})
+}
+
+func Test_Recorder_Redacted(t *testing.T) {
+	defer log.SetRedactor(nil)
+	log.SetRedactor(log.NewRedactor())
+
+	logger, recorder := NewLogger(rsFields)
+	logger.Info("login", log.Fields{"password": "abc"})
+
+	AssertField(t, recorder.AssertLogged(t, "login"), "properties.password", "[REDACTED]")
+}
+
+func Test_Recorder_Concurrent(t *testing.T) {
+	recorder := NewRecorder()
+	logger := log.NewFromCtxWithCustomerWriter(context.Background(), recorder)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("info_event")
+		}()
+	}
+	wg.Wait()
+
+	recorder.AssertCount(t, "info_event", 10)
+}
+
+func Test_Recorder_Monitor(t *testing.T) {
+	recorder := NewRecorder()
+	logger := monitor.NewWitCustomWriter(rsFields, recorder)
+
+	logger.Event("monitor_event").Fields(log.Fields{"name": "foo"}).Info("hello")
+
+	entry := recorder.AssertLogged(t, "monitor_event")
+	AssertField(t, entry, "properties.name", "foo")
+	AssertField(t, entry, "properties.message", "hello")
+}
@@ -0,0 +1,207 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Sink is a Writer that a MultiWriter forwards entries to
+type Sink struct {
+	// Writer receives every entry at or above MinSeverity
+	Writer Writer
+
+	// MinSeverity is the least severe entry this sink receives, eg. WarnSev. Empty means everything.
+	MinSeverity string
+
+	// BufferSize is the number of entries queued for this sink before new entries are dropped. Defaults to 1024.
+	BufferSize int
+}
+
+// MultiWriterConfig for setting initial values for MultiWriter
+type MultiWriterConfig struct {
+	Sinks []Sink
+
+	// OnPanic is called (from the sink's go routine) when a sink panics. Defaults to ignoring the panic.
+	OnPanic func(sink Writer, recovered interface{})
+}
+
+// MultiWriter forwards every entry to several Writers. Each sink is fed by its own AsyncWriter (a go routine and
+// queue) so that a slow or panicking sink cannot block or crash the caller or the other sinks.
+type MultiWriter struct {
+	sinks []multiSink
+}
+
+type multiSink struct {
+	Sink
+	queue *AsyncWriter
+}
+
+// NewMultiWriter creates a new MultiWriter and starts a go routine per sink.
+func NewMultiWriter(configure ...func(*MultiWriterConfig)) *MultiWriter {
+
+	conf := MultiWriterConfig{}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	writer := &MultiWriter{}
+	for _, sink := range conf.Sinks {
+		if sink.Writer == nil {
+			continue
+		}
+		if sink.BufferSize <= 0 {
+			sink.BufferSize = 1024
+		}
+
+		queue := newAsyncWriter(sink.BufferSize, OverflowDropNewest)
+		queue.sink = sink.Writer
+		if conf.OnPanic != nil {
+			onPanic, sinkWriter := conf.OnPanic, sink.Writer
+			queue.onPanic = func(recovered interface{}) {
+				onPanic(sinkWriter, recovered)
+			}
+		}
+		go queue.run()
+
+		writer.sinks = append(writer.sinks, multiSink{Sink: sink, queue: queue})
+	}
+
+	return writer
+}
+
+func (writer *MultiWriter) WriteFields(system Fields, fields ...Fields) {
+	severity, _ := system[Severity].(string)
+	properties := Fields{}.Merge(fields...)
+
+	for _, sink := range writer.sinks {
+		if !severityAtLeast(severity, sink.MinSeverity) {
+			continue
+		}
+
+		// writers are free to modify the maps they are given (and the caller can keep using theirs), so every sink gets
+		// its own deep copy
+		sink.queue.enqueue(asyncEntry{
+			system:     copyFields(system),
+			properties: copyFields(properties),
+		})
+	}
+}
+
+// Flush blocks until every entry queued before the call has been handed to each sink, then flushes any sink that
+// supports it (eg. AsyncWriter). Returns early with an error if the ctx is done.
+func (writer *MultiWriter) Flush(ctx context.Context) error {
+	for _, sink := range writer.sinks {
+		if err := sink.queue.Flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, sink := range writer.sinks {
+		if flusher, ok := sink.Writer.(interface{ Flush(context.Context) error }); ok {
+			if err := flusher.Flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Close drains every sink, stops their go routines and closes any sink that supports it (eg. a FileWriter, or another
+// MultiWriter). Returns an error if the ctx is done first, and a sink that is still draining is left open.
+func (writer *MultiWriter) Close(ctx context.Context) error {
+	var firstErr error
+
+	for _, sink := range writer.sinks {
+		err := sink.queue.close(ctx)
+		if err == nil {
+			err = closeSink(ctx, sink.Writer)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	UnregisterShutdownHook(writer.shutdownName())
+	return firstErr
+}
+
+func closeSink(ctx context.Context, sink Writer) error {
+	switch closer := sink.(type) {
+	case interface{ Close(context.Context) error }:
+		return closer.Close(ctx)
+	case io.Closer:
+		return closer.Close()
+	}
+	return nil
+}
+
+// RegisterShutdown registers a shutdown hook (see RegisterShutdownHook) that flushes and closes every sink, so
+// entries still queued are written when RunShutdownHooks is called or Fatal exits. Close unregisters it.
+func (writer *MultiWriter) RegisterShutdown() {
+	RegisterShutdownHook(writer.shutdownName(), func(ctx context.Context) error {
+		if err := writer.Flush(ctx); err != nil {
+			return err
+		}
+		return writer.Close(ctx)
+	})
+}
+
+func (writer *MultiWriter) shutdownName() string {
+	return fmt.Sprintf("log.MultiWriter(%p)", writer)
+}
+
+// Dropped returns the number of entries discarded across all sinks because their queue was full or closed.
+func (writer *MultiWriter) Dropped() uint64 {
+	var total uint64
+	for _, sink := range writer.sinks {
+		total += sink.queue.Dropped()
+	}
+	return total
+}
+
+// Panics returns the number of times a sink panicked while writing.
+func (writer *MultiWriter) Panics() uint64 {
+	var total uint64
+	for _, sink := range writer.sinks {
+		total += atomic.LoadUint64(&sink.queue.panics)
+	}
+	return total
+}
+
+// copyFields copies fields and any Fields, maps or slices nested in them
+func copyFields(fields Fields) Fields {
+	copied := make(Fields, len(fields))
+	for key, value := range fields {
+		copied[key] = copyValue(value)
+	}
+	return copied
+}
+
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case Fields:
+		return copyFields(v)
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			copied[key] = copyValue(nested)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, nested := range v {
+			copied[i] = copyValue(nested)
+		}
+		return copied
+	case []Fields:
+		copied := make([]Fields, len(v))
+		for i, nested := range v {
+			copied[i] = copyFields(nested)
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,216 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+type panicWriter struct{}
+
+func (writer panicWriter) WriteFields(system Fields, fields ...Fields) {
+	panic("sink exploded")
+}
+
+type slowWriter struct {
+	release chan struct{}
+}
+
+func (writer slowWriter) WriteFields(system Fields, fields ...Fields) {
+	<-writer.release
+}
+
+func Test_MultiWriter_AllSinks(t *testing.T) {
+
+	buffer1 := &bytes.Buffer{}
+	buffer2 := &bytes.Buffer{}
+
+	writer := NewMultiWriter(func(conf *MultiWriterConfig) {
+		conf.Sinks = []Sink{
+			{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = buffer1 })},
+			{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = buffer2 })},
+		}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("info_event", Fields{"string": "hello"})
+	writer.Close(context.Background())
+
+	for _, buffer := range []*bytes.Buffer{buffer1, buffer2} {
+		msg := buffer.String()
+		assertContainsString(t, msg, "event", "info_event")
+		assertContainsString(t, msg, "string", "hello")
+		assertScopeContainsSubDoc(t, msg, "properties")
+	}
+}
+
+func Test_MultiWriter_MinSeverity(t *testing.T) {
+
+	everything := &bytes.Buffer{}
+	errorsOnly := &bytes.Buffer{}
+
+	writer := NewMultiWriter(func(conf *MultiWriterConfig) {
+		conf.Sinks = []Sink{
+			{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = everything })},
+			{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = errorsOnly }), MinSeverity: ErrorSev},
+		}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Debug("debug_event")
+	logger.Warn("warn_event")
+	logger.Error("error_event", errors.New("bad"))
+	writer.Close(context.Background())
+
+	msg := everything.String()
+	assertContainsString(t, msg, "event", "debug_event")
+	assertContainsString(t, msg, "event", "warn_event")
+	assertContainsString(t, msg, "event", "error_event")
+
+	msg = errorsOnly.String()
+	assert.Assert(t, !strings.Contains(msg, "debug_event"), msg)
+	assert.Assert(t, !strings.Contains(msg, "warn_event"), msg)
+	assertContainsString(t, msg, "event", "error_event")
+}
+
+func Test_MultiWriter_PanickingSink(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	var recovered interface{}
+
+	writer := NewMultiWriter(func(conf *MultiWriterConfig) {
+		conf.Sinks = []Sink{
+			{Writer: panicWriter{}},
+			{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = buffer })},
+		}
+		conf.OnPanic = func(sink Writer, r interface{}) {
+			recovered = r
+		}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	logger.Info("first_event")
+	logger.Info("second_event")
+	writer.Close(context.Background())
+
+	assert.Assert(t, writer.Panics() == 2, writer.Panics())
+	assert.Assert(t, recovered == "sink exploded", recovered)
+
+	msg := buffer.String()
+	assertContainsString(t, msg, "event", "first_event")
+	assertContainsString(t, msg, "event", "second_event")
+}
+
+func Test_MultiWriter_SlowSink(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	slow := slowWriter{release: make(chan struct{})}
+
+	writer := NewMultiWriter(func(conf *MultiWriterConfig) {
+		conf.Sinks = []Sink{
+			{Writer: slow, BufferSize: 1},
+			{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = buffer })},
+		}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	// none of these block even though the slow sink never finishes its first write
+	for i := 0; i < 10; i++ {
+		logger.Info("info_event")
+	}
+	assert.Assert(t, writer.Dropped() >= 8, writer.Dropped())
+
+	// only the slow sink holds up Flush
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := writer.Flush(ctx)
+	assert.Assert(t, err == context.DeadlineExceeded, err)
+
+	close(slow.release)
+	writer.Close(context.Background())
+
+	assert.Assert(t, strings.Count(buffer.String(), "\n") == 10, buffer.String())
+}
+
+func Test_MultiWriter_CloseDeadline(t *testing.T) {
+
+	slow := slowWriter{release: make(chan struct{})}
+	defer close(slow.release)
+
+	writer := NewMultiWriter(func(conf *MultiWriterConfig) {
+		conf.Sinks = []Sink{{Writer: slow}}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("info_event")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := writer.Close(ctx)
+	assert.Assert(t, err == context.DeadlineExceeded, err)
+
+	// entries written after Close are dropped
+	logger.Info("dropped_event")
+	assert.Assert(t, writer.Dropped() == 1, writer.Dropped())
+}
+
+// mutatingWriter changes the maps it is given, including the nested ones
+type mutatingWriter struct{}
+
+func (writer mutatingWriter) WriteFields(system Fields, fields ...Fields) {
+	system[Event] = "changed"
+	for _, properties := range fields {
+		if nested, ok := properties["nested"].(Fields); ok {
+			nested["value"] = "changed"
+		}
+	}
+}
+
+func Test_MultiWriter_SinksGetCopies(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+
+	writer := NewMultiWriter(func(conf *MultiWriterConfig) {
+		conf.Sinks = []Sink{
+			{Writer: mutatingWriter{}},
+			{Writer: NewWriter(func(conf *WriterConfig) { conf.Output = buffer })},
+		}
+	})
+	logger := NewWitCustomWriter(rsFields, writer)
+
+	nested := Fields{"value": "original"}
+	logger.Info("info_event", Fields{"nested": nested})
+	assert.NilError(t, writer.Close(context.Background()))
+
+	assert.Equal(t, nested["value"], "original")
+	msg := buffer.String()
+	assertContainsString(t, msg, "event", "info_event")
+	assertContainsString(t, msg, "value", "original")
+}
+
+func Test_MultiWriter_RegisterShutdown(t *testing.T) {
+	defer resetFatal()
+	resetFatal()
+
+	memBuffer := &blockingBuffer{}
+	async := NewAsyncWriter(func(conf *AsyncWriterConfig) {
+		conf.Output = memBuffer
+	})
+	writer := NewMultiWriter(func(conf *MultiWriterConfig) {
+		conf.Sinks = []Sink{{Writer: async}}
+	})
+	writer.RegisterShutdown()
+	assert.Assert(t, len(shutdownHookNames()) == 1, shutdownHookNames())
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Info("info_event")
+
+	err := RunShutdownHooks(context.Background())
+	assert.Assert(t, err == nil, err)
+	assertContainsString(t, memBuffer.String(), "event", "info_event")
+	assert.Assert(t, len(shutdownHookNames()) == 0, shutdownHookNames())
+}
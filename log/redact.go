@@ -0,0 +1,333 @@
+package log
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/cultureamp/glamplify/helper"
+)
+
+// RedactStrategy controls what happens to a sensitive value
+type RedactStrategy int
+
+const (
+	// RedactMask replaces the sensitive value with RedactorConfig.Mask (default)
+	RedactMask RedactStrategy = iota
+	// RedactRemove removes the key (or slice element) entirely
+	RedactRemove
+	// RedactHash replaces the sensitive value with a salted sha256, so equal values can still be correlated
+	RedactHash
+)
+
+// Detector finds sensitive data inside string values, regardless of the key it was logged under
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Validate is optional, and lets a detector reject false positives (eg. a Luhn check for credit cards)
+	Validate func(match string) bool
+}
+
+var (
+	// JWTDetector finds JSON Web Tokens
+	JWTDetector = Detector{
+		Name:    "jwt",
+		Pattern: regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	}
+	// EmailDetector finds email addresses
+	EmailDetector = Detector{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	}
+	// CreditCardDetector finds 13 to 19 digit card numbers (optionally separated by spaces or dashes) that pass a Luhn check
+	CreditCardDetector = Detector{
+		Name:     "credit_card",
+		Pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Validate: luhnValid,
+	}
+	// AWSAccessKeyDetector finds AWS access key ids
+	AWSAccessKeyDetector = Detector{
+		Name:    "aws_access_key",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`),
+	}
+)
+
+// RedactorConfig for setting initial values for Redactor
+type RedactorConfig struct {
+	// Keys are case insensitive glob patterns (see path.Match) matched against the snake_case key, eg. "*_token"
+	Keys []string
+	// KeyPatterns are regular expressions matched against the snake_case key
+	KeyPatterns []*regexp.Regexp
+	// Detectors find sensitive data in string values
+	Detectors []Detector
+	// Strategy is applied to every match
+	Strategy RedactStrategy
+	// Mask replaces sensitive values when Strategy is RedactMask
+	Mask string
+	// Salt is prepended to values before hashing when Strategy is RedactHash
+	Salt string
+}
+
+// Redactor removes, masks or hashes sensitive data in Fields. It is off by default, see SetRedactor. It is safe for
+// concurrent use.
+type Redactor struct {
+	conf RedactorConfig
+}
+
+var defaultRedactor atomic.Value
+
+func init() {
+	SetRedactor(nil)
+}
+
+// NewRedactor creates a new Redactor. The optional configure func lets you change the deny lists, detectors and strategy.
+func NewRedactor(configure ...func(*RedactorConfig)) *Redactor {
+	conf := RedactorConfig{
+		Keys: []string{
+			"password", "*_password", "pwd", "passwd", "secret", "*_secret",
+			"token", "*_token", "api_key", "*_api_key", "authorization", "cookie", "set_cookie",
+		},
+		Detectors: []Detector{JWTDetector, EmailDetector, CreditCardDetector, AWSAccessKeyDetector},
+		Strategy:  RedactMask,
+		Mask:      "[REDACTED]",
+	}
+
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	for i, key := range conf.Keys {
+		conf.Keys[i] = strings.ToLower(key)
+	}
+
+	return &Redactor{conf: conf}
+}
+
+// SetRedactor changes the Redactor applied to the properties of every log entry (and by notify and monitor). Redaction
+// is off by default, pass nil to turn it back off.
+func SetRedactor(redactor *Redactor) {
+	defaultRedactor.Store(redactor)
+}
+
+// GetRedactor returns the Redactor applied to every log entry, so that other packages (eg. notify, monitor) can use the same rules.
+// It can return nil if redaction has been turned off.
+func GetRedactor() *Redactor {
+	redactor, _ := defaultRedactor.Load().(*Redactor)
+	return redactor
+}
+
+// Redact returns a copy of fields with sensitive keys and values redacted. The original fields are not modified.
+// A nil Redactor returns fields unchanged.
+func (redactor *Redactor) Redact(fields Fields) Fields {
+	if redactor == nil {
+		return fields
+	}
+	return redactor.redactFields(fields)
+}
+
+// KeyIsSensitive returns true if values logged under key would be redacted
+func (redactor *Redactor) KeyIsSensitive(key string) bool {
+	if redactor == nil {
+		return false
+	}
+
+	key = strings.ToLower(helper.ToSnakeCase(key))
+	for _, pattern := range redactor.conf.Keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	for _, pattern := range redactor.conf.KeyPatterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (redactor *Redactor) redactFields(fields Fields) Fields {
+	redacted := make(Fields, len(fields))
+
+	for k, v := range fields {
+		if redactor.KeyIsSensitive(k) {
+			if redactor.conf.Strategy != RedactRemove {
+				redacted[k] = redactor.replace(fmt.Sprint(v))
+			}
+			continue
+		}
+
+		if value, keep := redactor.redactValue(v); keep {
+			redacted[k] = value
+		}
+	}
+
+	return redacted
+}
+
+// redactValue returns the redacted value, and false if it should be removed
+func (redactor *Redactor) redactValue(value interface{}) (interface{}, bool) {
+
+	switch v := value.(type) {
+	case nil:
+		return nil, true
+	case string:
+		return redactor.redactString(v)
+	case Fields:
+		return redactor.redactFields(v), true
+	case map[string]interface{}:
+		return redactor.redactFields(v), true
+	case map[string]string:
+		fields := make(Fields, len(v))
+		for k, s := range v {
+			fields[k] = s
+		}
+		return redactor.redactFields(fields), true
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return v, true
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return value, true
+		}
+		if rv.Elem().Kind() == reflect.Struct {
+			// keep the pointer so any MarshalJSON on a pointer receiver is still used
+			return redactor.redactStruct(value)
+		}
+		return redactor.redactValue(rv.Elem().Interface())
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value, true
+		}
+		fields := make(Fields, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			fields[iter.Key().String()] = iter.Value().Interface()
+		}
+		return redactor.redactFields(fields), true
+
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// []byte is serialized as base64, nothing we can detect
+			return value, true
+		}
+		list := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			if item, keep := redactor.redactValue(rv.Index(i).Interface()); keep {
+				list = append(list, item)
+			}
+		}
+		return list, true
+
+	case reflect.Struct:
+		return redactor.redactStruct(value)
+
+	case reflect.String:
+		return redactor.redactString(rv.String())
+	}
+
+	return value, true
+}
+
+func (redactor *Redactor) redactStruct(value interface{}) (interface{}, bool) {
+	// round trip through json so we see exactly the keys that will be logged (honouring json tags)
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return value, true
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return value, true
+	}
+	return redactor.redactValue(generic)
+}
+
+func (redactor *Redactor) redactString(value string) (interface{}, bool) {
+	redacted, keep := redactor.redactText(value)
+	if !keep {
+		return nil, false
+	}
+	return redacted, true
+}
+
+// redactText returns the redacted string, and false if it should be removed
+func (redactor *Redactor) redactText(value string) (string, bool) {
+	if !redactor.anyDetectorMatches(value) {
+		// nothing to replace, so don't pay for ReplaceAllStringFunc copying the string
+		return value, true
+	}
+
+	redacted := value
+	found := false
+
+	for _, detector := range redactor.conf.Detectors {
+		redacted = detector.Pattern.ReplaceAllStringFunc(redacted, func(match string) string {
+			if detector.Validate != nil && !detector.Validate(match) {
+				return match
+			}
+			found = true
+			return redactor.replace(match)
+		})
+	}
+
+	if found && redactor.conf.Strategy == RedactRemove {
+		return "", false
+	}
+	return redacted, true
+}
+
+func (redactor *Redactor) anyDetectorMatches(value string) bool {
+	for _, detector := range redactor.conf.Detectors {
+		if detector.Pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (redactor *Redactor) replace(value string) string {
+	if redactor.conf.Strategy == RedactHash {
+		sum := sha256.Sum256([]byte(redactor.conf.Salt + value))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return redactor.conf.Mask
+}
+
+func luhnValid(number string) bool {
+	sum := 0
+	digits := 0
+	double := false
+
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,208 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/helper"
+)
+
+const (
+	// SamplingSummaryEvent is the event written at the end of each interval in which the Sampler suppressed entries
+	SamplingSummaryEvent = "log_sampling_summary"
+	// Suppressed is the key in the SamplingSummaryEvent containing the count of suppressed entries per event
+	Suppressed = "suppressed"
+)
+
+// SampleRate logs the First entries of each interval, then every Thereafter entry. A Thereafter of 0 drops the rest.
+type SampleRate struct {
+	First      int
+	Thereafter int
+}
+
+// SamplerConfig for setting initial values for Sampler
+type SamplerConfig struct {
+	// Interval after which the counts are reset and a summary is written
+	Interval time.Duration
+	// Writer the summary is written to, without any RequestScopedFields. Defaults to the writer log.New uses.
+	Writer Writer
+	// Rate applied to every event and severity
+	Rate SampleRate
+	// Events overrides the Rate for specific event names
+	Events map[string]SampleRate
+	// Exempt severities are never sampled
+	Exempt []string
+}
+
+// Sampler limits how many entries with the same event name and severity are written in each interval. It is safe
+// for concurrent use, and shared across every Logger via SetSampler. A go routine resets the counts each interval and
+// writes the summary, until Stop is called.
+type Sampler struct {
+	conf   SamplerConfig
+	exempt map[string]bool
+
+	mutex      sync.Mutex
+	counts     map[sampleKey]int
+	suppressed map[string]int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type sampleKey struct {
+	event    string
+	severity string
+}
+
+var defaultSampler atomic.Value
+
+func init() {
+	SetSampler(nil)
+}
+
+// NewSampler creates a new Sampler. By default each event/severity logs its first 100 entries per second, then every
+// 100th, and ERROR/FATAL are never sampled.
+func NewSampler(configure ...func(*SamplerConfig)) *Sampler {
+	conf := SamplerConfig{
+		Interval: time.Second,
+		Rate:     SampleRate{First: 100, Thereafter: 100},
+		Events:   map[string]SampleRate{},
+		Exempt:   []string{ErrorSev, FatalSev},
+	}
+
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	if conf.Interval <= 0 {
+		conf.Interval = time.Second
+	}
+	if conf.Writer == nil {
+		conf.Writer = internalWriter
+	}
+
+	events := make(map[string]SampleRate, len(conf.Events))
+	for event, rate := range conf.Events {
+		events[helper.ToSnakeCase(event)] = rate
+	}
+	conf.Events = events
+
+	exempt := map[string]bool{}
+	for _, sev := range conf.Exempt {
+		exempt[sev] = true
+	}
+
+	sampler := &Sampler{
+		conf:       conf,
+		exempt:     exempt,
+		counts:     map[sampleKey]int{},
+		suppressed: map[string]int{},
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go sampler.run()
+	return sampler
+}
+
+// SetSampler changes the Sampler applied to every Logger, and stops the one it replaces. Sampling is off by default,
+// pass nil to turn it off again.
+func SetSampler(sampler *Sampler) {
+	previous := GetSampler()
+	defaultSampler.Store(sampler)
+	if previous != sampler {
+		previous.Stop()
+	}
+}
+
+// GetSampler returns the Sampler applied to every Logger, or nil if sampling is off.
+func GetSampler() *Sampler {
+	sampler, _ := defaultSampler.Load().(*Sampler)
+	return sampler
+}
+
+// Stop writes the summary for the current interval and stops the go routine. Entries are still sampled afterwards,
+// but the counts are never reset.
+func (sampler *Sampler) Stop() {
+	if sampler == nil {
+		return
+	}
+
+	sampler.stopOnce.Do(func() {
+		close(sampler.stop)
+	})
+	<-sampler.done
+}
+
+func (sampler *Sampler) run() {
+	defer close(sampler.done)
+
+	ticker := time.NewTicker(sampler.conf.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			sampler.writeSummary(sampler.tick())
+		case <-sampler.stop:
+			sampler.writeSummary(sampler.tick())
+			return
+		}
+	}
+}
+
+// tick starts a new interval, returning the number of entries suppressed per event during the last one, if any were
+func (sampler *Sampler) tick() map[string]int {
+	sampler.mutex.Lock()
+	defer sampler.mutex.Unlock()
+
+	var summary map[string]int
+	if len(sampler.suppressed) > 0 {
+		summary = sampler.suppressed
+		sampler.suppressed = map[string]int{}
+	}
+	sampler.counts = map[sampleKey]int{}
+	return summary
+}
+
+func (sampler *Sampler) writeSummary(suppressed map[string]int) {
+	if suppressed == nil {
+		return
+	}
+	newLogger(gcontext.RequestScopedFields{}, sampler.conf.Writer).writeSamplingSummary(suppressed)
+}
+
+// sample returns true if the entry should be written
+func (sampler *Sampler) sample(event string, severity string) bool {
+	if sampler == nil {
+		return true
+	}
+
+	sampler.mutex.Lock()
+	defer sampler.mutex.Unlock()
+
+	if sampler.exempt[severity] {
+		return true
+	}
+
+	key := sampleKey{event: event, severity: severity}
+	sampler.counts[key]++
+	n := sampler.counts[key]
+
+	rate, ok := sampler.conf.Events[event]
+	if !ok {
+		rate = sampler.conf.Rate
+	}
+
+	if n <= rate.First {
+		return true
+	}
+	if rate.Thereafter > 0 && (n-rate.First)%rate.Thereafter == 0 {
+		return true
+	}
+
+	sampler.suppressed[event]++
+	return false
+}
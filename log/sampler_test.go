@@ -0,0 +1,143 @@
+package log
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func Test_Sampler_FirstThenEvery(t *testing.T) {
+	sampler := NewSampler(func(conf *SamplerConfig) {
+		conf.Rate = SampleRate{First: 3, Thereafter: 5}
+	})
+	defer sampler.Stop()
+
+	logged := 0
+	for i := 0; i < 23; i++ {
+		ok := sampler.sample("request_handled", InfoSev)
+		if ok {
+			logged++
+		}
+	}
+
+	// first 3, then the 8th, 13th, 18th and 23rd
+	assert.Assert(t, logged == 7, logged)
+}
+
+func Test_Sampler_KeyedOnEventAndSeverity(t *testing.T) {
+	sampler := NewSampler(func(conf *SamplerConfig) {
+		conf.Rate = SampleRate{First: 1}
+	})
+	defer sampler.Stop()
+
+	ok := sampler.sample("event_a", InfoSev)
+	assert.Assert(t, ok)
+	ok = sampler.sample("event_a", InfoSev)
+	assert.Assert(t, !ok)
+	ok = sampler.sample("event_a", WarnSev)
+	assert.Assert(t, ok)
+	ok = sampler.sample("event_b", InfoSev)
+	assert.Assert(t, ok)
+}
+
+func Test_Sampler_ExemptSeverities(t *testing.T) {
+	sampler := NewSampler(func(conf *SamplerConfig) {
+		conf.Rate = SampleRate{First: 0}
+	})
+	defer sampler.Stop()
+
+	for i := 0; i < 10; i++ {
+		ok := sampler.sample("failed", ErrorSev)
+		assert.Assert(t, ok)
+		ok = sampler.sample("failed", FatalSev)
+		assert.Assert(t, ok)
+	}
+}
+
+func Test_Sampler_EventOverride(t *testing.T) {
+	sampler := NewSampler(func(conf *SamplerConfig) {
+		conf.Rate = SampleRate{First: 1}
+		conf.Events = map[string]SampleRate{"importantEvent": {First: 100}}
+	})
+	defer sampler.Stop()
+
+	for i := 0; i < 10; i++ {
+		ok := sampler.sample("important_event", InfoSev)
+		assert.Assert(t, ok)
+	}
+}
+
+func Test_Sampler_Summary(t *testing.T) {
+	sampler := NewSampler(func(conf *SamplerConfig) {
+		conf.Interval = time.Hour
+		conf.Rate = SampleRate{First: 2}
+	})
+	defer sampler.Stop()
+
+	for i := 0; i < 5; i++ {
+		sampler.sample("noisy", InfoSev)
+	}
+	assert.Assert(t, !sampler.sample("noisy", InfoSev))
+
+	// a new interval resets the counts and reports what was suppressed
+	summary := sampler.tick()
+	assert.Assert(t, summary["noisy"] == 4, summary)
+	assert.Assert(t, sampler.sample("noisy", InfoSev))
+
+	// nothing suppressed, no summary
+	summary = sampler.tick()
+	assert.Assert(t, summary == nil, summary)
+}
+
+func Test_Sampler_Logger(t *testing.T) {
+	summaries := &blockingBuffer{}
+	defer SetSampler(nil)
+	SetSampler(NewSampler(func(conf *SamplerConfig) {
+		conf.Interval = 50 * time.Millisecond
+		conf.Rate = SampleRate{First: 2}
+		conf.Writer = NewWriter(func(conf *WriterConfig) {
+			conf.Output = summaries
+		})
+	}))
+
+	memBuffer, logger := newBufferLogger()
+	for i := 0; i < 10; i++ {
+		logger.Info("request_handled")
+		logger.Error("request_failed", errors.New("bad"))
+	}
+
+	msg := memBuffer.String()
+	assert.Assert(t, strings.Count(msg, `"event":"request_handled"`) == 2, msg)
+	assert.Assert(t, strings.Count(msg, `"event":"request_failed"`) == 10, msg)
+
+	// the summary is written when the interval ends, without waiting for another entry
+	time.Sleep(100 * time.Millisecond)
+	msg = summaries.String()
+	assertContainsString(t, msg, "event", SamplingSummaryEvent)
+	assertContainsInt(t, msg, "request_handled", 8)
+	assertContainsString(t, msg, "trace_id", "")
+	assert.Assert(t, !strings.Contains(msg, rsFields.TraceID), msg)
+	assert.Assert(t, !strings.Contains(memBuffer.String(), SamplingSummaryEvent), memBuffer.String())
+}
+
+func Test_Sampler_Stop(t *testing.T) {
+	summaries := &blockingBuffer{}
+	sampler := NewSampler(func(conf *SamplerConfig) {
+		conf.Interval = time.Hour
+		conf.Rate = SampleRate{First: 1}
+		conf.Writer = NewWriter(func(conf *WriterConfig) {
+			conf.Output = summaries
+		})
+	})
+
+	sampler.sample("noisy", InfoSev)
+	sampler.sample("noisy", InfoSev)
+
+	// stopping writes what was suppressed so far
+	sampler.Stop()
+	assertContainsInt(t, summaries.String(), "noisy", 1)
+	sampler.Stop()
+}
@@ -0,0 +1,214 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/cultureamp/glamplify/helper"
+)
+
+const (
+	// StrictEnv turns schema validation on at start up (with the default Schema) when set to "true"
+	StrictEnv = "LOG_STRICT"
+
+	// SchemaViolationEvent is the event written (as a WARN) after an entry that doesn't match the Schema
+	SchemaViolationEvent = "log_schema_violation"
+	// Violations is the key in the SchemaViolationEvent listing what was wrong with the entry
+	Violations = "violations"
+	// ViolatingEvent is the key in the SchemaViolationEvent with the event of the entry that was wrong
+	ViolatingEvent = "violating_event"
+)
+
+// FieldKind is the type a known key must have
+type FieldKind int
+
+const (
+	KindString FieldKind = iota
+	KindInt
+	KindNumber
+	KindBool
+	KindTime
+)
+
+func (kind FieldKind) String() string {
+	switch kind {
+	case KindString:
+		return "string"
+	case KindInt:
+		return "int"
+	case KindNumber:
+		return "number"
+	case KindBool:
+		return "bool"
+	case KindTime:
+		return "time"
+	}
+	return Unknown
+}
+
+// SchemaConfig for setting initial values for Schema
+type SchemaConfig struct {
+	// Reserved keys are written by the Logger itself (eg. severity, time, trace_id) and can't be used as properties
+	Reserved []string
+	// Types of the known keys, eg. time_taken_ms must be an int
+	Types map[string]FieldKind
+	// SnakeCase requires every property key (including nested ones) to already be snake_case, eg. customer_id not customerId
+	SnakeCase bool
+}
+
+// Schema checks the properties of every entry against the Culture Amp log standard. It is off by default, see
+// SetSchema. When an entry breaks the schema, any reserved keys are removed from its properties, the entry is written
+// and then a single SchemaViolationEvent lists everything that was wrong with it.
+type Schema struct {
+	conf     SchemaConfig
+	reserved map[string]bool
+}
+
+var defaultSchema atomic.Value
+
+func init() {
+	SetSchema(nil)
+	if enabled, err := strconv.ParseBool(os.Getenv(StrictEnv)); err == nil && enabled {
+		SetSchema(NewSchema())
+	}
+}
+
+// NewSchema creates a new Schema. By default the system keys are reserved, the standard keys in constants.go have to
+// have the right type and every key must be snake_case. The optional configure func lets you change these.
+func NewSchema(configure ...func(*SchemaConfig)) *Schema {
+	conf := SchemaConfig{
+		Reserved: []string{
+			Time, Event, Severity, Resource, Os, Caller, Exception, Properties,
+			TraceID, RequestID, CorrelationID, Customer, User,
+			Product, App, AppVer, AwsRegion, AwsAccountID,
+		},
+		Types: map[string]FieldKind{
+			Message:             KindString,
+			TimeTaken:           KindString,
+			TimeTakenMS:         KindInt,
+			MemoryUsed:          KindInt,
+			MemoryAvail:         KindInt,
+			ItemsProcessed:      KindInt,
+			TotalItemsProcessed: KindInt,
+			TotalItemsRequested: KindInt,
+		},
+		SnakeCase: true,
+	}
+
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	reserved := make(map[string]bool, len(conf.Reserved))
+	for _, key := range conf.Reserved {
+		reserved[key] = true
+	}
+
+	return &Schema{
+		conf:     conf,
+		reserved: reserved,
+	}
+}
+
+// SetSchema changes the Schema every entry is checked against. Validation is off by default, pass nil to turn it
+// off again.
+func SetSchema(schema *Schema) {
+	defaultSchema.Store(schema)
+}
+
+// GetSchema returns the Schema every entry is checked against, or nil if validation is off.
+func GetSchema() *Schema {
+	schema, _ := defaultSchema.Load().(*Schema)
+	return schema
+}
+
+// Validate returns a description of each way properties break the schema, sorted so they are stable. A nil Schema
+// returns nothing.
+func (schema *Schema) Validate(properties Fields) []string {
+	if schema == nil {
+		return nil
+	}
+
+	var violations []string
+	for key, value := range properties {
+		snaked := helper.ToSnakeCase(key)
+
+		if schema.reserved[snaked] {
+			violations = append(violations, fmt.Sprintf("%q is reserved and can't be a property", key))
+			continue
+		}
+
+		if kind, ok := schema.conf.Types[snaked]; ok && !kind.matches(value) {
+			violations = append(violations, fmt.Sprintf("%q must be %s, not %T", key, kind, value))
+		}
+	}
+
+	if schema.conf.SnakeCase {
+		violations = append(violations, schema.snakeCaseViolations("", properties)...)
+	}
+
+	sort.Strings(violations)
+	return violations
+}
+
+func (schema *Schema) snakeCaseViolations(prefix string, fields Fields) []string {
+	var violations []string
+	for key, value := range fields {
+		if snaked := helper.ToSnakeCase(key); snaked != key {
+			violations = append(violations, fmt.Sprintf("%q should be snake_case %q", prefix+key, prefix+snaked))
+		}
+		if nested, ok := value.(Fields); ok {
+			violations = append(violations, schema.snakeCaseViolations(prefix+key+".", nested)...)
+		}
+	}
+	return violations
+}
+
+// removeReserved returns properties without any reserved keys, so they can't be mistaken for the system values
+func (schema *Schema) removeReserved(properties Fields) Fields {
+	cleaned := Fields{}
+	for key, value := range properties {
+		if !schema.reserved[helper.ToSnakeCase(key)] {
+			cleaned[key] = value
+		}
+	}
+	return cleaned
+}
+
+func (kind FieldKind) matches(value interface{}) bool {
+	switch kind {
+	case KindString:
+		_, ok := value.(string)
+		return ok
+	case KindInt:
+		switch v := value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			return true
+		case json.Number:
+			_, err := v.Int64()
+			return err == nil
+		}
+	case KindNumber:
+		switch value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+			return true
+		}
+	case KindBool:
+		_, ok := value.(bool)
+		return ok
+	case KindTime:
+		switch v := value.(type) {
+		case time.Time:
+			return true
+		case string:
+			_, err := time.Parse(time.RFC3339, v)
+			return err == nil
+		}
+	}
+	return false
+}
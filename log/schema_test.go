@@ -0,0 +1,106 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func Test_Schema_Off(t *testing.T) {
+	memBuffer, logger := newBufferLogger()
+
+	logger.Info("info_event", Fields{Severity: "made up", "customerId": "abc"})
+	assert.Assert(t, !strings.Contains(memBuffer.String(), SchemaViolationEvent), memBuffer.String())
+}
+
+func Test_Schema_Validate(t *testing.T) {
+	schema := NewSchema()
+
+	violations := schema.Validate(Fields{
+		Severity:       "made up",
+		"traceId":      "1-2-3",
+		"customerId":   "abc",
+		TimeTakenMS:    "12",
+		MemoryUsed:     int64(100),
+		Message:        "all good",
+		ItemsProcessed: 3.5,
+		"nested": Fields{
+			"innerKey": 1,
+		},
+	})
+
+	assert.DeepEqual(t, violations, []string{
+		`"customerId" should be snake_case "customer_id"`,
+		`"items_processed" must be int, not float64`,
+		`"nested.innerKey" should be snake_case "nested.inner_key"`,
+		`"severity" is reserved and can't be a property`,
+		`"time_taken_ms" must be int, not string`,
+		`"traceId" is reserved and can't be a property`,
+		`"traceId" should be snake_case "trace_id"`,
+	})
+
+	assert.Assert(t, len(schema.Validate(NewDurationFields(time.Second))) == 0)
+	assert.Assert(t, len(schema.Validate(Fields{"customer_id": "abc"})) == 0)
+
+	var off *Schema
+	assert.Assert(t, len(off.Validate(Fields{Severity: "made up"})) == 0)
+}
+
+func Test_Schema_Config(t *testing.T) {
+	schema := NewSchema(func(conf *SchemaConfig) {
+		conf.Reserved = []string{"tenant"}
+		conf.Types = map[string]FieldKind{"retry": KindBool, "started": KindTime, "score": KindNumber}
+		conf.SnakeCase = false
+	})
+
+	violations := schema.Validate(Fields{
+		Severity:  "fine now",
+		"Tenant":  "abc",
+		"retry":   "yes",
+		"started": "yesterday",
+		"score":   1.5,
+		"camelOk": true,
+	})
+
+	assert.DeepEqual(t, violations, []string{
+		`"Tenant" is reserved and can't be a property`,
+		`"retry" must be bool, not string`,
+		`"started" must be time, not string`,
+	})
+}
+
+func Test_Schema_Strict(t *testing.T) {
+	defer SetSchema(nil)
+	SetSchema(NewSchema())
+
+	memBuffer, logger := newBufferLogger()
+
+	logger.Info("info_event", Fields{Severity: "made up", "customerId": "abc"})
+
+	lines := strings.Split(strings.TrimSpace(memBuffer.String()), "\n")
+	assert.Equal(t, len(lines), 2, memBuffer.String())
+
+	// the entry is written without the reserved key
+	assertContainsString(t, lines[0], "event", "info_event")
+	assertContainsString(t, lines[0], "severity", "INFO")
+	assertContainsString(t, lines[0], "customer_id", "abc")
+	assert.Assert(t, !strings.Contains(lines[0], "made up"), lines[0])
+
+	// followed by a single diagnostic
+	assertContainsString(t, lines[1], "event", SchemaViolationEvent)
+	assertContainsString(t, lines[1], "severity", WarnSev)
+	assertContainsString(t, lines[1], ViolatingEvent, "info_event")
+	assert.Assert(t, strings.Contains(lines[1], `should be snake_case`), lines[1])
+	assert.Assert(t, strings.Contains(lines[1], `is reserved`), lines[1])
+
+	memBuffer.Reset()
+	logger.InfoWith("info_event", String("customer_id", "abc"))
+	logger.Event("import").StartWithMemory().Success("done")
+	assert.Assert(t, !strings.Contains(memBuffer.String(), SchemaViolationEvent), memBuffer.String())
+
+	memBuffer.Reset()
+	logger.InfoWith("info_event", Int(TimeTaken, 12))
+	assertContainsString(t, memBuffer.String(), "event", SchemaViolationEvent)
+}
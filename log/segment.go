@@ -1,9 +1,21 @@
 package log
 
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
 type Segment struct {
 	logger Logger
 	event  string
 	fields Fields
+
+	started        time.Time
+	trackMemory    bool
+	startMemory    runtime.MemStats
+	itemsProcessed int64
+	itemsRequested int64
 }
 
 func (segment *Segment) Fields(fields ...Fields) *Segment {
@@ -60,4 +72,91 @@ func (segment *Segment) Fatal(err error) {
 	)
 }
 
+// Start begins timing the segment, eg. seg := logger.Event("import_users").Start() then seg.Success(...) or seg.Fail(err)
+// when done. Success and Fail add time_taken, time_taken_ms and the item counters if they were set.
+func (segment *Segment) Start() *Segment {
+	segment.started = time.Now()
+	return segment
+}
+
+// StartWithMemory is Start that also adds memory_used and memory_available (the change in bytes since the start).
+// It reads the runtime memory stats at the start and finish, which stops the world briefly, so use it for long running
+// jobs rather than every request. memory_used is the heap growth, so it is 0 if the GC freed more than was allocated.
+func (segment *Segment) StartWithMemory() *Segment {
+	segment.trackMemory = true
+	runtime.ReadMemStats(&segment.startMemory)
+	return segment.Start()
+}
+
+// ItemsProcessed adds to the items_processed count. It is safe to call from many go routines.
+func (segment *Segment) ItemsProcessed(count int) *Segment {
+	atomic.AddInt64(&segment.itemsProcessed, int64(count))
+	return segment
+}
+
+// TotalItemsRequested sets total_items_requested
+func (segment *Segment) TotalItemsRequested(count int) *Segment {
+	atomic.StoreInt64(&segment.itemsRequested, int64(count))
+	return segment
+}
+
+// Success writes the segment as INFO with the message, timings and counters
+func (segment *Segment) Success(message string) {
+
+	segment.fields = segment.fields.Merge(segment.finish())
+	segment.fields[Message] = message
 
+	segment.logger.Info(
+		segment.event,
+		segment.fields,
+	)
+}
+
+// Fail writes the segment as ERROR with the err, timings and counters
+func (segment *Segment) Fail(err error) {
+
+	segment.fields = segment.fields.Merge(segment.finish())
+
+	segment.logger.Error(
+		segment.event,
+		err,
+		segment.fields,
+	)
+}
+
+func (segment *Segment) finish() Fields {
+	fields := Fields{}
+
+	if !segment.started.IsZero() {
+		fields = NewDurationFields(time.Since(segment.started))
+	}
+
+	if segment.trackMemory {
+		var memory runtime.MemStats
+		runtime.ReadMemStats(&memory)
+		fields[MemoryUsed] = heapGrowth(segment.startMemory, memory)
+		fields[MemoryAvail] = available(memory) - available(segment.startMemory)
+	}
+
+	if processed := atomic.LoadInt64(&segment.itemsProcessed); processed > 0 {
+		fields[ItemsProcessed] = processed
+	}
+	if requested := atomic.LoadInt64(&segment.itemsRequested); requested > 0 {
+		fields[TotalItemsRequested] = requested
+	}
+
+	return fields
+}
+
+// heapGrowth is how much the heap grew, or 0 if it shrank (a GC ran and freed more than was allocated)
+func heapGrowth(start runtime.MemStats, finish runtime.MemStats) int64 {
+	if finish.HeapAlloc < start.HeapAlloc {
+		return 0
+	}
+	return int64(finish.HeapAlloc - start.HeapAlloc)
+}
+
+// available is the memory obtained from the OS that isn't in use by the heap
+func available(memory runtime.MemStats) int64 {
+	return int64(memory.Sys) - int64(memory.HeapAlloc)
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"github.com/cultureamp/glamplify/context"
 	"gotest.tools/assert"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cultureamp/glamplify/log"
+	"github.com/cultureamp/glamplify/log/logtest"
 )
 
 func Test_Segment_Debug(t *testing.T) {
@@ -109,6 +112,76 @@ func Test_Segment_WithNoFields(t *testing.T) {
 	assertContainsString(t, msg, "message", "nothing to write home about")
 }
 
+func Test_Segment_Success(t *testing.T) {
+	logger, recorder := logtest.NewLogger(context.RequestScopedFields{})
+
+	segment := logger.Event("import_users").Fields(log.Fields{"source": "csv"}).Start()
+	segment.TotalItemsRequested(10)
+	segment.ItemsProcessed(4)
+	segment.ItemsProcessed(5)
+	time.Sleep(10 * time.Millisecond)
+	segment.Success("imported")
+
+	entry := recorder.AssertLogged(t, "import_users")
+	assert.Equal(t, entry.Severity, log.InfoSev)
+	logtest.AssertField(t, entry, "properties.message", "imported")
+	logtest.AssertField(t, entry, "properties.source", "csv")
+	logtest.AssertField(t, entry, "properties.items_processed", 9)
+	logtest.AssertField(t, entry, "properties.total_items_requested", 10)
+
+	ms, _ := entry.Property(log.TimeTakenMS)
+	assert.Assert(t, ms.(float64) >= 10, ms)
+	taken, _ := entry.Property(log.TimeTaken)
+	assert.Assert(t, strings.HasPrefix(taken.(string), "P0.0"), taken)
+
+	// memory is opt-in
+	logtest.AssertNoField(t, entry, "properties.memory_used")
+	logtest.AssertNoField(t, entry, "properties.memory_available")
+}
+
+func Test_Segment_StartWithMemory(t *testing.T) {
+	logger, recorder := logtest.NewLogger(context.RequestScopedFields{})
+
+	segment := logger.Event("import_users").StartWithMemory()
+	runtime.GC()
+	segment.Success("imported")
+
+	entry := recorder.AssertLogged(t, "import_users")
+	used, ok := entry.Property(log.MemoryUsed)
+	assert.Assert(t, ok)
+	assert.Assert(t, used.(float64) >= 0, used)
+	_, ok = entry.Property(log.MemoryAvail)
+	assert.Assert(t, ok)
+
+	_, ok = entry.Property(log.TimeTakenMS)
+	assert.Assert(t, ok)
+}
+
+func Test_Segment_Fail(t *testing.T) {
+	logger, recorder := logtest.NewLogger(context.RequestScopedFields{})
+
+	segment := logger.Event("import_users").Start()
+	segment.Fail(errors.New("file not found"))
+
+	entry := recorder.AssertLogged(t, "import_users")
+	assert.Equal(t, entry.Severity, log.ErrorSev)
+	logtest.AssertField(t, entry, "exception.error", "file not found")
+	logtest.AssertNoField(t, entry, "properties.items_processed")
+
+	_, ok := entry.Property(log.TimeTakenMS)
+	assert.Assert(t, ok)
+}
+
+func Test_Segment_NotStarted(t *testing.T) {
+	logger, recorder := logtest.NewLogger(context.RequestScopedFields{})
+
+	logger.Event("import_users").Success("imported")
+
+	entry := recorder.AssertLogged(t, "import_users")
+	logtest.AssertNoField(t, entry, "properties.time_taken")
+	logtest.AssertNoField(t, entry, "properties.memory_used")
+}
+
 func getTestLogger() (*bytes.Buffer, *log.Logger) {
 	rsFields := context.RequestScopedFields{
 		TraceID:             "1-2-3",
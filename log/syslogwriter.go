@@ -0,0 +1,327 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogFacility is the syslog facility entries are written with
+type SyslogFacility int
+
+const (
+	FacilityUser   SyslogFacility = 1
+	FacilityDaemon SyslogFacility = 3
+	FacilityLocal0 SyslogFacility = 16
+	FacilityLocal1 SyslogFacility = 17
+	FacilityLocal2 SyslogFacility = 18
+	FacilityLocal3 SyslogFacility = 19
+	FacilityLocal4 SyslogFacility = 20
+	FacilityLocal5 SyslogFacility = 21
+	FacilityLocal6 SyslogFacility = 22
+	FacilityLocal7 SyslogFacility = 23
+)
+
+// syslogSeverities maps our severities to RFC5424 severities
+var syslogSeverities = map[string]int{
+	DebugSev: 7, // debug
+	InfoSev:  6, // informational
+	WarnSev:  4, // warning
+	ErrorSev: 3, // error
+	FatalSev: 2, // critical
+}
+
+// SyslogSeverity returns the RFC5424 severity (0-7) for DebugSev..FatalSev
+func SyslogSeverity(severity string) int {
+	if sev, ok := syslogSeverities[severity]; ok {
+		return sev
+	}
+	return syslogSeverities[InfoSev]
+}
+
+// SyslogWriterConfig for setting initial values for SyslogWriter
+type SyslogWriterConfig struct {
+	// Network is "udp" (default), "tcp", "unix" (stream) or "unixgram"
+	Network string
+	// Address is host:port for udp and tcp, or the socket path for unix and unixgram
+	Address string
+	// Facility defaults to FacilityUser
+	Facility SyslogFacility
+	// AppName defaults to the "app" system value (APP), otherwise the name of the executable
+	AppName string
+	// StructuredDataID is the SD-ID the fields are written under
+	StructuredDataID string
+	// Timeout for connecting and writing
+	Timeout time.Duration
+}
+
+// SyslogWriter writes RFC5424 messages to a syslog daemon (eg. rsyslog). The system values and properties are carried
+// as structured data (eg. properties.user_id="123"), and the message is the event followed by any "message" property.
+// Stream connections use octet counting framing (RFC6587). It is safe for concurrent use, and reconnects if a write fails.
+type SyslogWriter struct {
+	mutex sync.Mutex
+	conf  SyslogWriterConfig
+	conn  net.Conn
+	pid   string
+}
+
+// NewSyslogWriter connects to the syslog daemon. The optional configure func lets you set the network, address, facility etc.
+func NewSyslogWriter(configure ...func(*SyslogWriterConfig)) (*SyslogWriter, error) {
+
+	conf := SyslogWriterConfig{
+		Network:          "udp",
+		Address:          "localhost:514",
+		Facility:         FacilityUser,
+		StructuredDataID: "fields@32473",
+		Timeout:          5 * time.Second,
+	}
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	writer := &SyslogWriter{
+		conf: conf,
+		pid:  strconv.Itoa(os.Getpid()),
+	}
+
+	if err := writer.connect(); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (writer *SyslogWriter) WriteFields(system Fields, fields ...Fields) {
+	msg := writer.format(system, fields...)
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.write(msg) == nil {
+		return
+	}
+
+	// the daemon may have restarted, so try once more on a new connection
+	if writer.connect() == nil {
+		writer.write(msg)
+	}
+}
+
+// Close closes the connection to the syslog daemon
+func (writer *SyslogWriter) Close() error {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	if writer.conn == nil {
+		return nil
+	}
+	err := writer.conn.Close()
+	writer.conn = nil
+	return err
+}
+
+// connect must be called with the mutex held (or before the writer is shared)
+func (writer *SyslogWriter) connect() error {
+	if writer.conn != nil {
+		writer.conn.Close()
+		writer.conn = nil
+	}
+
+	conn, err := net.DialTimeout(writer.conf.Network, writer.conf.Address, writer.conf.Timeout)
+	if err != nil {
+		return err
+	}
+	writer.conn = conn
+	return nil
+}
+
+// write must be called with the mutex held
+func (writer *SyslogWriter) write(msg string) error {
+	if writer.conn == nil {
+		return fmt.Errorf("syslog writer is not connected")
+	}
+
+	if writer.conf.Timeout > 0 {
+		writer.conn.SetWriteDeadline(time.Now().Add(writer.conf.Timeout))
+	}
+
+	if writer.isStream() {
+		// RFC6587 octet counting
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	// This can return an error, but we just swallow it here as what can we or a client really do? Try and log it? :)
+	_, err := writer.conn.Write([]byte(msg))
+	return err
+}
+
+func (writer *SyslogWriter) isStream() bool {
+	switch writer.conf.Network {
+	case "tcp", "tcp4", "tcp6", "unix":
+		return true
+	}
+	return false
+}
+
+// format returns <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID name="value"...] MSG
+func (writer *SyslogWriter) format(system Fields, fields ...Fields) string {
+	entry := entryFields(system, fields...)
+	severity := fieldString(entry[Severity])
+
+	var sb strings.Builder
+	sb.WriteByte('<')
+	sb.WriteString(strconv.Itoa(int(writer.conf.Facility)*8 + SyslogSeverity(severity)))
+	sb.WriteString(">1 ")
+	sb.WriteString(syslogHeader(fieldString(entry[Time]), 128))
+	sb.WriteByte(' ')
+	sb.WriteString(syslogHeader(fieldString(entry[Resource]), 255))
+	sb.WriteByte(' ')
+	sb.WriteString(syslogHeader(writer.appName(entry), 48))
+	sb.WriteByte(' ')
+	sb.WriteString(writer.pid)
+	sb.WriteByte(' ')
+	sb.WriteString(syslogHeader(fieldString(entry[Event]), 32))
+	sb.WriteByte(' ')
+
+	flat := map[string]interface{}{}
+	flatten("", entry, flat)
+	// already in the header
+	delete(flat, Time)
+	delete(flat, Event)
+
+	sb.WriteByte('[')
+	sb.WriteString(writer.conf.StructuredDataID)
+	for _, key := range sortedKeys(flat) {
+		value := fieldString(flat[key])
+		if value == "" {
+			continue
+		}
+		sb.WriteByte(' ')
+		sb.WriteString(syslogParamName(key))
+		sb.WriteString(`="`)
+		sb.WriteString(syslogParamValue(value))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte(']')
+
+	if msg := entryMessage(entry); msg != "" {
+		sb.WriteByte(' ')
+		sb.WriteString(msg)
+	}
+
+	return sb.String()
+}
+
+func (writer *SyslogWriter) appName(entry Fields) string {
+	if writer.conf.AppName != "" {
+		return writer.conf.AppName
+	}
+	if app := fieldString(entry[App]); app != "" {
+		return app
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// entryFields returns the system values with the properties nested under "properties" in snake_case, the same as an
+// Encoder is given
+func entryFields(system Fields, fields ...Fields) Fields {
+	entry := system.Merge()
+	properties := Fields{}.Merge(fields...)
+	if len(properties) > 0 {
+		entry[Properties] = properties
+	}
+	return entry.ToSnakeCase()
+}
+
+// entryMessage is the event, followed by the "message" property if there is one
+func entryMessage(entry Fields) string {
+	msg := fieldString(entry[Event])
+	if properties, ok := entry[Properties].(Fields); ok {
+		if message := fieldString(properties[Message]); message != "" {
+			if msg != "" {
+				msg += ": "
+			}
+			msg += message
+		}
+	}
+	return msg
+}
+
+// fieldString returns a value as plain text, using json for anything that isn't a simple type
+func fieldString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return fmt.Sprint(v)
+	}
+
+	buf, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(buf)
+}
+
+func sortedKeys(flat map[string]interface{}) []string {
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// syslogHeader returns a header value of printable ascii (without spaces), or "-" (the nil value) if empty
+func syslogHeader(value string, max int) string {
+	var sb strings.Builder
+	for i := 0; i < len(value) && sb.Len() < max; i++ {
+		if c := value[i]; c > ' ' && c < 0x7f {
+			sb.WriteByte(c)
+		}
+	}
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
+}
+
+// syslogParamName returns an SD-NAME, which is printable ascii except '=', ' ', ']' and '"', up to 32 characters
+func syslogParamName(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name) && sb.Len() < 32; i++ {
+		c := name[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// syslogParamValue escapes '"', '\' and ']'
+func syslogParamValue(value string) string {
+	if !strings.ContainsAny(value, `"\]`) {
+		return value
+	}
+
+	var sb strings.Builder
+	for _, r := range value {
+		switch r {
+		case '"', '\\', ']':
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
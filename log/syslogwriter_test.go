@@ -0,0 +1,133 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func Test_SyslogWriter_UDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close()
+
+	writer, err := NewSyslogWriter(func(conf *SyslogWriterConfig) {
+		conf.Network = "udp"
+		conf.Address = listener.LocalAddr().String()
+		conf.Facility = FacilityLocal3
+	})
+	assert.NilError(t, err)
+	defer writer.Close()
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Event("user_created").Fields(Fields{"user_id": 123, "note": `say "hi" [ok]`}).Warn("created a user")
+
+	buf := make([]byte, 65536)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	assert.NilError(t, err)
+	msg := string(buf[:n])
+
+	// local3 * 8 + warning
+	assert.Assert(t, strings.HasPrefix(msg, "<156>1 "), msg)
+	assert.Assert(t, strings.Contains(msg, " murmur "+strconv.Itoa(os.Getpid())+" user_created [fields@32473 "), msg)
+	assert.Assert(t, strings.Contains(msg, ` properties.user_id="123"`), msg)
+	assert.Assert(t, strings.Contains(msg, ` properties.note="say \"hi\" [ok\]"`), msg)
+	assert.Assert(t, strings.Contains(msg, ` trace_id="1-2-3"`), msg)
+	assert.Assert(t, strings.HasSuffix(msg, "] user_created: created a user"), msg)
+}
+
+func Test_SyslogWriter_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		var msgs []string
+		for i := 0; i < 2; i++ {
+			msg, err := readOctetCounted(reader)
+			if err != nil {
+				break
+			}
+			msgs = append(msgs, msg)
+		}
+		received <- msgs
+	}()
+
+	writer, err := NewSyslogWriter(func(conf *SyslogWriterConfig) {
+		conf.Network = "tcp"
+		conf.Address = listener.Addr().String()
+		conf.AppName = "my app"
+	})
+	assert.NilError(t, err)
+	defer writer.Close()
+
+	logger := NewWitCustomWriter(rsFields, writer)
+	logger.Debug("debug_event")
+	logger.Error("error_event", errors.New("it broke\nbadly"))
+
+	var msgs []string
+	select {
+	case msgs = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+
+	assert.Assert(t, len(msgs) == 2, msgs)
+	assert.Assert(t, strings.HasPrefix(msgs[0], "<15>1 "), msgs[0])
+	assert.Assert(t, strings.Contains(msgs[0], " myapp "), msgs[0])
+	assert.Assert(t, strings.HasPrefix(msgs[1], "<11>1 "), msgs[1])
+	assert.Assert(t, strings.Contains(msgs[1], ` exception.error="it broke`), msgs[1])
+}
+
+func Test_SyslogWriter_NoListener(t *testing.T) {
+	writer, err := NewSyslogWriter(func(conf *SyslogWriterConfig) {
+		conf.Network = "tcp"
+		conf.Address = "127.0.0.1:1"
+	})
+	assert.Assert(t, writer == nil, writer)
+	assert.Assert(t, err != nil, err)
+}
+
+func Test_SyslogSeverity(t *testing.T) {
+	assert.Equal(t, SyslogSeverity(DebugSev), 7)
+	assert.Equal(t, SyslogSeverity(InfoSev), 6)
+	assert.Equal(t, SyslogSeverity(WarnSev), 4)
+	assert.Equal(t, SyslogSeverity(ErrorSev), 3)
+	assert.Equal(t, SyslogSeverity(FatalSev), 2)
+	assert.Equal(t, SyslogSeverity("unknown"), 6)
+}
+
+// readOctetCounted reads one RFC6587 octet counted message eg. "12 <14>1 - ..."
+func readOctetCounted(reader *bufio.Reader) (string, error) {
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", fmt.Errorf("bad length '%s'", length)
+	}
+
+	buf := make([]byte, n)
+	_, err = io.ReadFull(reader, buf)
+	return string(buf), err
+}
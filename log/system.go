@@ -25,7 +25,9 @@ func newSystemValues() *SystemValues {
 	return &SystemValues{}
 }
 
-func (df SystemValues) getSystemValues(rsFields gcontext.RequestScopedFields, event string, sev string) Fields {
+// getSystemValues skip is the number of logging frames above it (see entrySkip), so the caller (if enabled) is the
+// code that wrote the entry
+func (df SystemValues) getSystemValues(rsFields gcontext.RequestScopedFields, event string, sev string, skip int) Fields {
 	fields := Fields{
 		Time:     df.timeNow(RFC3339Milli),
 		Event:    event,
@@ -34,32 +36,57 @@ func (df SystemValues) getSystemValues(rsFields gcontext.RequestScopedFields, ev
 		Severity: sev,
 	}
 
+	if CallerEnabled() {
+		// +1 for getSystemValues itself
+		if caller, ok := callerAt(skip + 1); ok {
+			fields[Caller] = caller.fields()
+		}
+	}
+
 	fields = df.getMandatoryFields(rsFields, fields)
 	fields = df.getEnvFields(fields)
 
 	return fields
 }
 
+// getErrorValues adds the exception, which has the message and type of err and of every error it wraps (see
+// errors.Unwrap), the stack trace captured when the error was created if there is one (otherwise the stack of the
+// logging go routine), any fields attached to the errors and, if enabled, GC stats.
 func (df SystemValues) getErrorValues(err error, fields Fields) Fields {
 	errorMessage := strings.TrimSpace(err.Error())
+	chain := newErrorChain(err)
 
-	stats := &debug.GCStats{}
-	buf := debug.Stack()
-	debug.ReadGCStats(stats)
-
-	fields[Exception] = Fields{
-		"error":    errorMessage,
-		"trace":    string(buf),
-		"gc_stats": Fields{
-			"last_gc": stats.LastGC,
-			"num_gc": stats.NumGC,
-			"pause_total": stats.PauseTotal,
-			"pause_history": stats.Pause,
-			"pause_end": stats.PauseEnd,
+	exception := Fields{
+		"error":  errorMessage,
+		"type":   fmt.Sprintf("%T", err),
+		"causes": chain.causes,
+	}
+
+	if chain.trace != "" {
+		exception["trace"] = chain.trace
+	} else {
+		exception["trace"] = string(debug.Stack())
+	}
+
+	if len(chain.fields) > 0 {
+		exception["fields"] = GetRedactor().Redact(chain.fields)
+	}
+
+	if GCStatsEnabled() {
+		stats := &debug.GCStats{}
+		debug.ReadGCStats(stats)
+
+		exception["gc_stats"] = Fields{
+			"last_gc":        stats.LastGC,
+			"num_gc":         stats.NumGC,
+			"pause_total":    stats.PauseTotal,
+			"pause_history":  stats.Pause,
+			"pause_end":      stats.PauseEnd,
 			"page_quantiles": stats.PauseQuantiles,
-		},
+		}
 	}
 
+	fields[Exception] = exception
 	return fields
 }
 
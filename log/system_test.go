@@ -2,7 +2,10 @@ package log
 
 import (
 	"errors"
+	"fmt"
 	"gotest.tools/assert"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -19,7 +22,7 @@ func Test_HostName(t *testing.T) {
 func Test_Default(t *testing.T) {
 	df := newSystemValues()
 
-	fields := df.getSystemValues(rsFields, "event_name", DebugSev)
+	fields := df.getSystemValues(rsFields, "event_name", DebugSev, 0)
 
 	_, ok := fields[Time]
 	assert.Assert(t, ok, "missing 'time' in default fields")
@@ -52,13 +55,183 @@ func Test_Default(t *testing.T) {
 func Test_ErrorDefault(t *testing.T) {
 	df := newSystemValues()
 
-	fields := df.getSystemValues(rsFields, "event_name", DebugSev)
+	fields := df.getSystemValues(rsFields, "event_name", DebugSev, 0)
 	fields = df.getErrorValues(errors.New("test err"), fields)
 
 	_, ok := fields[Exception]
 	assert.Assert(t, ok, "missing 'exception' in default fields")
 }
 
+// stackError mimics a pkg/errors error, with a StackTrace() method returning a slice of program counters
+type stackTrace []frame
+type frame uintptr
+
+type stackError struct {
+	msg   string
+	stack []uintptr
+}
+
+func newStackError(msg string) *stackError {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	return &stackError{msg: msg, stack: pcs[:n]}
+}
+
+func (e *stackError) Error() string {
+	return e.msg
+}
+
+func (e *stackError) StackTrace() stackTrace {
+	trace := make(stackTrace, len(e.stack))
+	for i, pc := range e.stack {
+		trace[i] = frame(pc)
+	}
+	return trace
+}
+
+type causeError struct {
+	msg   string
+	cause error
+}
+
+func (e causeError) Error() string {
+	return e.msg + ": " + e.cause.Error()
+}
+
+func (e causeError) Cause() error {
+	return e.cause
+}
+
+// joinedErrors unwraps to a list, the same as errors.Join (Go 1.20) does
+type joinedErrors []error
+
+func (e joinedErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e joinedErrors) Unwrap() []error {
+	return e
+}
+
+type loopError struct{}
+
+func (e *loopError) Error() string {
+	return "loop"
+}
+
+func (e *loopError) Unwrap() error {
+	return e
+}
+
+func Test_ErrorChain(t *testing.T) {
+	df := newSystemValues()
+
+	root := newStackError("connection refused")
+	err := fmt.Errorf("load user: %w", causeError{msg: "query failed", cause: root})
+
+	fields := df.getErrorValues(err, Fields{})
+	exception := fields[Exception].(Fields)
+
+	assert.Equal(t, exception["error"], "load user: query failed: connection refused")
+	assert.Equal(t, exception["type"], "*fmt.wrapError")
+
+	causes := exception["causes"].([]Fields)
+	assert.Assert(t, len(causes) == 3, causes)
+	assert.Equal(t, causes[1]["type"], "log.causeError")
+	assert.Equal(t, causes[1]["message"], "query failed: connection refused")
+	assert.Equal(t, causes[2]["type"], "*log.stackError")
+	assert.Equal(t, causes[2]["message"], "connection refused")
+
+	// the stack is where the root error was created, not where it was logged
+	trace := exception["trace"].(string)
+	assert.Assert(t, strings.Contains(trace, "log.Test_ErrorChain()"), trace)
+	assert.Assert(t, !strings.Contains(trace, "getErrorValues"), trace)
+
+	_, ok := exception["gc_stats"]
+	assert.Assert(t, !ok, "gc_stats should be opt-in")
+}
+
+func Test_ErrorChain_Fields(t *testing.T) {
+	defer SetRedactor(nil)
+	SetRedactor(NewRedactor())
+
+	df := newSystemValues()
+
+	root := errors.New("not found")
+	inner := AttachFields(root, Fields{"user_id": 1, "table": "users", "password": "abc"})
+	err := AttachFields(fmt.Errorf("load user: %w", inner), Fields{"user_id": 2})
+
+	fields := df.getErrorValues(err, Fields{})
+	exception := fields[Exception].(Fields)
+
+	errFields := exception["fields"].(Fields)
+	assert.Equal(t, errFields["user_id"], 2)
+	assert.Equal(t, errFields["table"], "users")
+	assert.Equal(t, errFields["password"], "[REDACTED]")
+
+	// AttachFields only repeats the message of the error it wraps, so isn't listed as a cause
+	causes := exception["causes"].([]Fields)
+	assert.Assert(t, len(causes) == 2, causes)
+	assert.Assert(t, errors.Is(err, root))
+	assert.Assert(t, AttachFields(nil, Fields{}) == nil)
+}
+
+func Test_ErrorChain_Joined(t *testing.T) {
+	df := newSystemValues()
+
+	first := AttachFields(errors.New("user not found"), Fields{"user_id": 1})
+	second := fmt.Errorf("load account: %w", causeError{msg: "query failed", cause: errors.New("timeout")})
+	err := fmt.Errorf("sync: %w", joinedErrors{first, second})
+
+	fields := df.getErrorValues(err, Fields{})
+	exception := fields[Exception].(Fields)
+
+	// every branch is walked, first to last
+	causes := exception["causes"].([]Fields)
+	var messages []string
+	for _, cause := range causes {
+		messages = append(messages, cause["message"].(string))
+	}
+	assert.DeepEqual(t, messages, []string{
+		"sync: user not found\nload account: query failed: timeout",
+		"user not found\nload account: query failed: timeout",
+		"user not found",
+		"load account: query failed: timeout",
+		"query failed: timeout",
+		"timeout",
+	})
+
+	errFields := exception["fields"].(Fields)
+	assert.Equal(t, errFields["user_id"], 1)
+}
+
+func Test_ErrorChain_Loop(t *testing.T) {
+	df := newSystemValues()
+
+	fields := df.getErrorValues(&loopError{}, Fields{})
+	exception := fields[Exception].(Fields)
+
+	causes := exception["causes"].([]Fields)
+	assert.Assert(t, len(causes) == 1, causes)
+	assert.Assert(t, strings.Contains(exception["trace"].(string), "getErrorValues"), exception["trace"])
+}
+
+func Test_ErrorGCStats(t *testing.T) {
+	defer SetGCStatsEnabled(false)
+	SetGCStatsEnabled(true)
+
+	df := newSystemValues()
+	fields := df.getErrorValues(errors.New("test err"), Fields{})
+	exception := fields[Exception].(Fields)
+
+	_, ok := exception["gc_stats"]
+	assert.Assert(t, ok, "missing 'gc_stats'")
+}
+
 func Test_DurationAsIso8601(t *testing.T) {
 
 	d := time.Millisecond * 456
@@ -9,12 +9,17 @@ import (
 // WriterConfig for setting initial values for Logger
 type WriterConfig struct {
 	Output io.Writer
+
+	// Encoder formats each entry. When nil, LOG_FORMAT is used if set, otherwise ConsoleEncoder if Output is a
+	// terminal, otherwise JSONEncoder.
+	Encoder Encoder
 }
 
 // FieldWriter wraps the standard library writer and add structured types as quoted key value pairs
 type FieldWriter struct {
 	mutex      sync.Mutex
 	output     io.Writer
+	encoder    Encoder
 }
 
 type Writer interface  {
@@ -38,17 +43,16 @@ func NewWriter(configure ...func(*WriterConfig)) *FieldWriter { // https://dave.
 	defer writer.mutex.Unlock()
 
 	writer.output = conf.Output
+	writer.encoder = conf.Encoder
+	if writer.encoder == nil {
+		writer.encoder = defaultEncoder(conf.Output)
+	}
 
 	return writer
 }
 
 func (writer *FieldWriter) WriteFields(system Fields, fields ...Fields) {
-	merged := Fields{}
-	properties := merged.Merge(fields...)
-	if len(properties) > 0 {
-		system[Properties] = properties
-	}
-	str := system.ToSnakeCase().ToJson()
+	str := serializeFields(writer.encoder, system, fields...)
 	writer.write(str)
 }
 
@@ -57,18 +61,44 @@ func (writer *FieldWriter) write(str string) {
 	// Note: Making this faster is a good thing (while we are a sync writer - async writer is a different story)
 	// So we don't use the stdlib writer.Print(), but rather have our own optimized version
 	// Which does less, but is 3-10x faster
+	buffer := toLine(str)
+
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+
+	// This can return an error, but we just swallow it here as what can we or a client really do? Try and log it? :)
+	writer.output.Write(buffer)
+}
 
+func serializeFields(encoder Encoder, system Fields, fields ...Fields) string {
+	merged := Fields{}
+	properties := merged.Merge(fields...)
+	if len(properties) > 0 {
+		system[Properties] = properties
+	}
+	return encoder.Encode(system.ToSnakeCase())
+}
+
+func toLine(str string) []byte {
 	// alloc a slice to contain the string and possible '\n'
 	length := len(str)
 	buffer := make([]byte, length+1)
 	copy(buffer[:], str)
-	if len(str) == 0 || str[length-1] != '\n' {
-		copy(buffer[length:], "\n")
+	if length > 0 && str[length-1] == '\n' {
+		return buffer[:length]
 	}
+	copy(buffer[length:], "\n")
+	return buffer
+}
+
+func (writer *FieldWriter) jsonEncoder() (JSONEncoder, bool) {
+	encoder, ok := writer.encoder.(JSONEncoder)
+	return encoder, ok
+}
 
+func (writer *FieldWriter) writeLine(line []byte) {
 	writer.mutex.Lock()
 	defer writer.mutex.Unlock()
 
-	// This can return an error, but we just swallow it here as what can we or a client really do? Try and log it? :)
-	writer.output.Write(buffer)
+	writer.output.Write(line)
 }
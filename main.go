@@ -89,7 +89,7 @@ func main() {
 	req, _ := http.NewRequest("GET", pattern, nil)
 	h.ServeHTTP(rr, req)
 
-	app.Shutdown()
+	app.Shutdown(context.Background())
 }
 
 func rootRequestHandler(w http.ResponseWriter, r *http.Request) {
@@ -2,17 +2,19 @@ package monitor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/cultureamp/glamplify/log"
-
-	newrelic "github.com/newrelic/go-agent"
 )
 
 const (
-	waitFORNR = 4 * time.Second
+	// defaultWaitTimeout is how long WaitForConnection and Shutdown wait in total if the ctx has no deadline
+	defaultWaitTimeout = 5 * time.Second
 )
 
 // Labels are key value pairs used to roll up applications into specific categories
@@ -46,14 +48,18 @@ type Config struct {
 	// https://docs.newrelic.com/docs/serverless-function-monitoring/aws-lambda-monitoring/get-started/introduction-new-relic-monitoring-aws-lambda
 	ServerlessMode bool `yaml:"serverless_mode"`
 
+	// Backend is where transactions and events are sent. Defaults to New Relic (using the settings above), or see
+	// NewOpenTelemetryBackend and NewNoopBackend.
+	Backend Backend `yaml:"-"`
+
 	// coreLogger logger
 	logger *monitorLogger
 }
 
 // Application is a wrapper over the underlying implementation
 type Application struct {
-	impl newrelic.Application
-	conf Config
+	backend Backend
+	conf    Config
 }
 
 // NewApplication creates a new Application - you should only create 1 Application per process
@@ -71,31 +77,12 @@ func NewApplication(name string, configure ...func(*Config)) (*Application, erro
 		config(&conf)
 	}
 
-	cfg := newrelic.NewConfig(name, conf.License)
-	cfg.Enabled = conf.Enabled // useful to turn on/off in test/dev vs production accounts
-	cfg.License = conf.License
-	cfg.CustomInsightsEvents.Enabled = true // otherwise custom events won't fire
-	cfg.ErrorCollector.Enabled = true
-	cfg.ErrorCollector.CaptureEvents = true
-	cfg.HighSecurity = false // HighSecurity blocks sending custom events
-	cfg.Labels = conf.Labels // camp, environment, data classification, etc
-	cfg.RuntimeSampler.Enabled = true
-	cfg.ServerlessMode.Enabled = conf.ServerlessMode
-	cfg.TransactionTracer.Enabled = true
-	cfg.Utilization.DetectAWS = true
-	cfg.Utilization.DetectDocker = true
-
-	// for now we turn off DistributedTracing because it is too expensive
-	cfg.DistributedTracer.Enabled = false
-
 	if conf.Logging {
 		//cfg.Logger = newrelic.NewDebugLogger(os.Stdout) <- this writes JSON to Stdout :(
 		// So we have our own implementation that wraps our standard logger
 		// TODO - the context here is missing traceID, correlationID, etc
 		conf.logger = newMonitorLogger(context.Background())
-		cfg.Logger = conf.logger
-
-		cfg.Logger.Debug("configuration", log.Fields{
+		conf.logger.Debug("configuration", log.Fields{
 			"enabled":         conf.Enabled,
 			"logging":         conf.Logging,
 			"labels":          conf.Labels,
@@ -107,55 +94,42 @@ func NewApplication(name string, configure ...func(*Config)) (*Application, erro
 		conf: conf,
 	}
 
-	impl, err := newrelic.NewApplication(cfg)
+	if conf.Backend != nil {
+		app.backend = conf.Backend
+		return app, nil
+	}
+
+	// in server mode newrelic.NewApplication connects to NR in the background, and any custom events
+	// recorded before it has connected are dropped. Call WaitForConnection if that matters.
+	backend, err := newNewRelicBackend(name, conf)
 	if err != nil {
 		app.logError("Failed to create Application", err)
 		return nil, err
 	}
 
-	if !conf.ServerlessMode {
-		// if conf.ServerlessMode = false (server mode) then newrelic.NewApplication spins up
-		// some go routines that make a network call back to NR. Until this happens any "RecordCustomEvents"
-		// seem to get dropped!
-		// Waiting here so that everything is set up and ready
-		time.Sleep(waitFORNR)
-	}
-
-	app.impl = impl
-	return app, err
+	app.backend = backend
+	return app, nil
 }
 
 // RecordEvent sends a custom event with the associated data to the underlying implementation
 func (app Application) RecordEvent(eventType string, fields log.Fields) error {
 	app.log("record_event_begin", log.Fields{"event_type": eventType}, fields)
 
-	// NewRelic has limits on number and size of entries
-	// https://docs.newrelic.com/docs/insights/insights-data-sources/custom-data/insights-custom-data-requirements-limits
-	// However, if you pass in a string entry longer than 255 it fails "siliently"!!!!!
-	// TODO - implement our own checking?
-
-	ok, err := fields.ValidateNewRelic()
-	if !ok {
-		app.logError("record_event_error", err)
-		app.log("record_event_end", log.Fields{"event_type": eventType}, fields)
-		return err
-	}
-
-	err = app.impl.RecordCustomEvent(eventType, fields)
+	fields = log.GetRedactor().Redact(fields)
+	err := app.backend.RecordCustomEvent(eventType, fields)
 	app.logError("record_event_error", err)
 	app.log("record_event_end", log.Fields{"event_type": eventType}, fields)
 
 	return err
 }
 
-// Adds a new NR transaction when used as middleware
+// Adds a new transaction when used as middleware
 func (app *Application) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		txn := app.startTransaction(r.URL.Path, w, r)
+		ctx, txn := app.startTransaction(r.Context(), r.URL.Path, w, r)
 		defer txn.End()
 
-		r = txn.addToHTTPContext(r)
-		next.ServeHTTP(txn, r)
+		next.ServeHTTP(txn, r.WithContext(ctx))
 	})
 }
 
@@ -165,49 +139,143 @@ func (app *Application) WrapHTTPHandler(pattern string, handler func(http.Respon
 	return p, func(w http.ResponseWriter, r *http.Request) { h.ServeHTTP(w, r) }
 }
 
-// Shutdown flushes any remaining data to the SAAS endpoint
-func (app Application) Shutdown() {
+// StartBackgroundTransaction starts a Transaction that isn't a web request, eg. for a queue consumer or scheduled job.
+// The returned ctx holds it, so monitor.TxnFromContext finds it. Call txn.End() when done, or see RunJob.
+func (app *Application) StartBackgroundTransaction(ctx context.Context, name string) (context.Context, *Transaction) {
+	return app.startTransaction(ctx, name, nil, nil)
+}
+
+// RunJob calls job in a background Transaction named name, reporting any error it returns. A panic is reported and the
+// Transaction ended before it is passed on, so the process still exits (or recovers) as it would have.
+func (app *Application) RunJob(ctx context.Context, name string, job func(ctx context.Context) error) error {
+	ctx, txn := app.StartBackgroundTransaction(ctx, name)
+	defer txn.End()
+
+	defer func() {
+		if r := recover(); r != nil {
+			txn.ReportError(fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+	}()
+
+	err := job(ctx)
+	if err != nil {
+		txn.ReportError(err)
+	}
+	return err
+}
+
+// WaitForConnection blocks until the Backend is ready to send. For NR, in server mode this happens in the background
+// after NewApplication returns and custom events recorded before then are dropped. It returns an error if the
+// connection failed (eg. a bad license) or the ctx is done first. A disabled or serverless Application is always ready.
+func (app Application) WaitForConnection(ctx context.Context) error {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	err := wait(ctx, app.backend.WaitForConnection)
+	app.logError("wait_for_connection_error", err)
+	return err
+}
+
+// Shutdown sends any pending events, errors and log entries, waiting until they are sent or the ctx is done. It returns
+// an error if data could not be sent, eg. the Application never connected or the ctx deadline passed first. Every step
+// shares the one deadline.
+func (app Application) Shutdown(ctx context.Context) error {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	var errs []string
+
+	// monitor.Logger entries go to New Relic, so a disabled Application has none to send
+	if app.conf.Enabled {
+		if err := FlushLogs(ctx); err != nil {
+			errs = append(errs, "logs: "+err.Error())
+		}
+	}
+
+	// NR only sends the pending data if it is connected, so give it until the deadline to connect. A Backend that has
+	// already failed to connect returns straight away, leaving the time for Shutdown.
+	if err := app.WaitForConnection(ctx); err != nil {
+		errs = append(errs, "not connected: "+err.Error())
+	}
+
+	if err := wait(ctx, app.backend.Shutdown); err != nil {
+		errs = append(errs, "shutdown: "+err.Error())
+	}
 
-	if !app.conf.ServerlessMode {
-		// if conf.ServerlessMode = false (server mode) then newrelic.Shutdown can exit its coreLogger go routines
-		// before it has sent all pending data!
-		// Waiting here so that everything is sent before we start closing down...
-		time.Sleep(waitFORNR)
+	if len(errs) == 0 {
+		return nil
 	}
+	err := errors.New("monitor shutdown failed: " + strings.Join(errs, ", "))
+	app.logError("shutdown_error", err)
+	return err
+}
 
-	// The time duration passed here is how long to wait before the shutdown channel processes the request
-	// It is NOT how long to wait to send data before shutting down.
-	app.impl.Shutdown(30 * time.Second)
+// RegisterShutdown registers Shutdown as a shutdown hook (see log.RegisterShutdownHook), so pending events, errors and
+// log entries are sent when log.RunShutdownHooks is called or log.Fatal exits
+func (app *Application) RegisterShutdown() {
+	log.RegisterShutdownHook(fmt.Sprintf("monitor.Application(%p)", app), app.Shutdown)
+}
+
+// withDefaultTimeout gives ctx a deadline of defaultWaitTimeout, unless it already has one
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, defaultWaitTimeout)
+}
+
+// wait calls f with the time left until the ctx deadline, returning ctx.Err() if the ctx is done before f returns.
+// f keeps running in the background after that, but the Backend bounds it by the timeout anyway.
+func wait(ctx context.Context, f func(timeout time.Duration) error) error {
+	timeout := defaultWaitTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if timeout < 0 {
+		timeout = 0
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- f(timeout)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (app *Application) wrapHTTPHandler(pattern string, handler http.Handler) (string, http.Handler) {
 	return pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		txn := app.startTransaction(pattern, w, r)
+		ctx, txn := app.startTransaction(r.Context(), pattern, w, r)
 		defer txn.End()
 
-		r = txn.addToHTTPContext(r)
-		handler.ServeHTTP(txn, r)
+		handler.ServeHTTP(txn, r.WithContext(ctx))
 	})
 }
 
-func (app *Application) startTransaction(name string, w http.ResponseWriter, r *http.Request) Transaction {
+// startTransaction starts a transaction with the Backend, and returns a ctx carrying both it and our wrapper
+func (app *Application) startTransaction(ctx context.Context, name string, w http.ResponseWriter, r *http.Request) (context.Context, *Transaction) {
 	app.log("transaction_start", log.Fields{
 		"txn_name": name,
 	})
 
-	// Create our wrapper txn and add it to the HTTP ctx
-	txn := Transaction{
+	// Create our wrapper txn and add it to the ctx
+	txn := &Transaction{
 		app:     app,
 		name:    name,
 		logging: app.conf.Logging,
 		logger:  app.conf.logger,
 	}
 
-	// call the NR implementation
-	impl := app.impl.StartTransaction(name, w, r)
-	txn.impl = impl
+	// call the Backend implementation
+	ctx, txn.impl = app.backend.StartTransaction(ctx, name, w, r)
 
-	return txn
+	return txn.addToContext(ctx), txn
 }
 
 func (app *Application) addToContext(ctx context.Context) context.Context {
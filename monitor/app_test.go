@@ -1,9 +1,14 @@
 package monitor_test
 
 import (
+	"context"
+	"errors"
 	"github.com/cultureamp/glamplify/log"
 	"github.com/cultureamp/glamplify/monitor"
+	"github.com/cultureamp/glamplify/monitor/monitortest"
+	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/assert"
 )
@@ -24,7 +29,7 @@ func TestApplication_RecordEvent_Server_Success(t *testing.T) {
 	})
 	assert.Assert(t, err == nil, err)
 
-	app.Shutdown()
+	app.Shutdown(context.Background())
 }
 
 func TestApplication_RecordEvent_Server_Fail(t *testing.T) {
@@ -43,6 +48,125 @@ func TestApplication_RecordEvent_Server_Fail(t *testing.T) {
 	})
 	assert.Assert(t, err != nil, err)
 
-	app.Shutdown()
+	app.Shutdown(context.Background())
 }
 
+
+func TestApplication_Disabled_NoWait(t *testing.T) {
+	start := time.Now()
+	app, err := monitor.NewApplication("Glamplify-Unit-Tests", func(conf *monitor.Config) {
+		conf.Enabled = false
+		conf.ServerlessMode = false
+	})
+	assert.Assert(t, err == nil, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	err = app.WaitForConnection(ctx)
+	assert.Assert(t, err == nil, err)
+
+	err = app.Shutdown(ctx)
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, time.Since(start) < time.Second, time.Since(start))
+}
+
+func TestApplication_NotConnected_Error(t *testing.T) {
+	start := time.Now()
+	app, err := monitor.NewApplication("Glamplify-Unit-Tests", func(conf *monitor.Config) {
+		conf.Enabled = true
+		conf.License = strings.Repeat("0", 40) // valid format, but NR will never accept it
+		conf.ServerlessMode = false
+	})
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, time.Since(start) < time.Second, time.Since(start))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err = app.WaitForConnection(ctx)
+	assert.Assert(t, err != nil)
+
+	err = app.Shutdown(ctx)
+	assert.Assert(t, err != nil)
+	assert.Assert(t, strings.Contains(err.Error(), "not connected"), err)
+	assert.Assert(t, time.Since(start) < 2*time.Second, time.Since(start))
+}
+
+func TestApplication_RecordEvent_Recorded(t *testing.T) {
+	defer log.SetRedactor(nil)
+	log.SetRedactor(log.NewRedactor())
+
+	app, recorder := monitortest.NewApplication()
+
+	err := app.RecordEvent("glamplify_unittest_customevent", log.Fields{
+		"aString":  "hello world",
+		"password": "secret",
+	})
+	assert.Assert(t, err == nil, err)
+
+	event := recorder.AssertEvent(t, "glamplify_unittest_customevent")
+	monitortest.AssertEventField(t, event, "aString", "hello world")
+	monitortest.AssertEventField(t, event, "password", "[REDACTED]")
+}
+
+func TestApplication_BackgroundTransaction(t *testing.T) {
+	app, recorder := monitortest.NewApplication()
+
+	ctx, txn := app.StartBackgroundTransaction(context.Background(), "sqs_consumer")
+
+	found, err := monitor.TxnFromContext(ctx)
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, found == txn)
+
+	found.AddAttributes(log.Fields{"message_count": 10})
+	txn.End()
+
+	recorded := recorder.AssertTransaction(t, "sqs_consumer")
+	assert.Assert(t, !recorded.Web)
+	assert.Assert(t, recorded.Ended)
+	monitortest.AssertAttribute(t, recorded, "message_count", 10)
+}
+
+func TestApplication_RunJob(t *testing.T) {
+	app, recorder := monitortest.NewApplication()
+
+	err := app.RunJob(context.Background(), "nightly_report", func(ctx context.Context) error {
+		txn, err := monitor.TxnFromContext(ctx)
+		assert.Assert(t, err == nil, err)
+		defer txn.StartSegment("build_report").End()
+		return nil
+	})
+	assert.Assert(t, err == nil, err)
+
+	txn := recorder.AssertTransaction(t, "nightly_report")
+	assert.Assert(t, txn.Ended)
+	assert.Assert(t, len(txn.Errors) == 0, txn.Errors)
+	assert.Equal(t, len(txn.Segments), 1)
+
+	err = app.RunJob(context.Background(), "failed_job", func(ctx context.Context) error {
+		return errors.New("it broke")
+	})
+	assert.Error(t, err, "it broke")
+
+	txn = recorder.AssertTransaction(t, "failed_job")
+	assert.Assert(t, txn.Ended)
+	monitortest.AssertError(t, txn, "it broke")
+}
+
+func TestApplication_RunJob_Panic(t *testing.T) {
+	app, recorder := monitortest.NewApplication()
+
+	defer func() {
+		r := recover()
+		assert.Equal(t, r, "out of cheese")
+
+		txn := recorder.AssertTransaction(t, "panicky_job")
+		assert.Assert(t, txn.Ended)
+		monitortest.AssertError(t, txn, "panic: out of cheese")
+	}()
+
+	app.RunJob(context.Background(), "panicky_job", func(ctx context.Context) error {
+		panic("out of cheese")
+	})
+}
@@ -0,0 +1,118 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cultureamp/glamplify/log"
+)
+
+// Backend is the monitoring service an Application sends its transactions and events to. NewApplication uses New
+// Relic unless Config.Backend is set, eg. to NewOpenTelemetryBackend() or NewNoopBackend().
+type Backend interface {
+	// StartTransaction starts timing name, and returns a ctx carrying the transaction. w and r are nil for a
+	// transaction that isn't a web request.
+	StartTransaction(ctx context.Context, name string, w http.ResponseWriter, r *http.Request) (context.Context, BackendTransaction)
+
+	// RecordCustomEvent sends an event that isn't part of a transaction
+	RecordCustomEvent(eventType string, fields log.Fields) error
+
+	// WaitForConnection blocks until the backend can send data, or the timeout has passed
+	WaitForConnection(timeout time.Duration) error
+
+	// Shutdown sends any pending data, waiting at most timeout
+	Shutdown(timeout time.Duration) error
+}
+
+// contextBackend is a Backend that can start transactions we don't know about (eg. New Relic's lambda wrapper), and
+// find them again in the ctx
+type contextBackend interface {
+	// TransactionFromContext returns the transaction the backend added to ctx, or nil if there isn't one
+	TransactionFromContext(ctx context.Context) BackendTransaction
+}
+
+// BackendTransaction is a single transaction started by a Backend. Writes to it go to the response of a web request
+// (if there is one), so the backend can record the status code.
+type BackendTransaction interface {
+	http.ResponseWriter
+
+	// AddAttribute adds a key value pair to the transaction
+	AddAttribute(key string, value interface{}) error
+
+	// NoticeError records err against the transaction. class and fields are optional.
+	NoticeError(err error, class string, fields log.Fields) error
+
+	// StartSegment starts timing part of the transaction, eg. a function call
+	StartSegment(name string) TxnSegment
+
+	// StartDatastoreSegment starts timing a database call. product is the database (eg. "Postgres"), collection the
+	// table and operation eg. "select". query is optional, and should have its parameters replaced by placeholders.
+	StartDatastoreSegment(product string, collection string, operation string, query string) TxnSegment
+
+	// StartExternalSegment starts timing an outgoing web request. It may add headers to req, so the service it calls
+	// can continue the trace.
+	StartExternalSegment(req *http.Request) TxnSegment
+
+	// End finishes the transaction
+	End() error
+}
+
+// TxnSegment is part of a Transaction, timed until End is called, eg. defer txn.StartSegment("load_users").End()
+type TxnSegment interface {
+	End()
+}
+
+// ExternalSegment is a TxnSegment returned by StartExternalSegment that can record the response (eg. its status
+// code). Call SetResponse before End. NewRoundTripper does this for every request.
+type ExternalSegment interface {
+	TxnSegment
+
+	// SetResponse records the response to the outgoing request
+	SetResponse(resp *http.Response)
+}
+
+// responseWriter passes writes on to the response (if there is one), and remembers the status code, for backends
+// that don't wrap the response themselves
+type responseWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	status int
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{w: w}
+}
+
+// Header delegates to the wrapped response
+func (writer *responseWriter) Header() http.Header {
+	if writer.w != nil {
+		return writer.w.Header()
+	}
+	if writer.header == nil {
+		writer.header = http.Header{}
+	}
+	return writer.header
+}
+
+// Write delegates to the wrapped response
+func (writer *responseWriter) Write(bytes []byte) (int, error) {
+	if writer.status == 0 {
+		writer.WriteHeader(http.StatusOK)
+	}
+	if writer.w == nil {
+		return len(bytes), nil
+	}
+	return writer.w.Write(bytes)
+}
+
+// WriteHeader delegates to the wrapped response
+func (writer *responseWriter) WriteHeader(statusCode int) {
+	if writer.status != 0 {
+		return
+	}
+	writer.status = statusCode
+	if writer.w != nil {
+		writer.w.WriteHeader(statusCode)
+	}
+}
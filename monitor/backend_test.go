@@ -0,0 +1,158 @@
+package monitor_test
+
+import (
+	"context"
+	"errors"
+	"github.com/cultureamp/glamplify/log"
+	"github.com/cultureamp/glamplify/monitor"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gotest.tools/assert"
+)
+
+func TestBackend_Noop(t *testing.T) {
+	app, err := monitor.NewApplication("Glamplify-Unit-Tests", func(conf *monitor.Config) {
+		conf.Backend = monitor.NewNoopBackend()
+	})
+	assert.Assert(t, err == nil, err)
+
+	_, handler := app.WrapHTTPHandler("/", func(w http.ResponseWriter, r *http.Request) {
+		txn, err := monitor.TxnFromRequest(w, r)
+		assert.Assert(t, err == nil, err)
+		assert.Assert(t, txn.AddAttributes(log.Fields{"aString": "hello world"}) == nil)
+		assert.Assert(t, txn.ReportError(errors.New("bad")) == nil)
+
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("short and stout"))
+	})
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	handler(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusTeapot)
+	assert.Equal(t, rr.Body.String(), "short and stout")
+
+	assert.Assert(t, app.RecordEvent("glamplify_unittest_customevent", log.Fields{"aInt": 123}) == nil)
+	assert.Assert(t, app.Shutdown(context.Background()) == nil)
+}
+
+func TestBackend_OpenTelemetry(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	app, err := monitor.NewApplication("Glamplify-Unit-Tests", func(conf *monitor.Config) {
+		conf.Backend = monitor.NewOpenTelemetryBackend(func(conf *monitor.OpenTelemetryConfig) {
+			conf.TracerProvider = provider
+			conf.Propagator = propagation.TraceContext{}
+		})
+	})
+	assert.Assert(t, err == nil, err)
+
+	_, handler := app.WrapHTTPHandler("/users", func(w http.ResponseWriter, r *http.Request) {
+		txn, err := monitor.TxnFromRequest(w, r)
+		assert.Assert(t, err == nil, err)
+
+		// the span is in the request ctx, so other OpenTelemetry instrumentation can use it
+		assert.Assert(t, trace.SpanFromContext(r.Context()).SpanContext().IsValid())
+
+		txn.AddAttributes(log.Fields{"user_count": 3})
+		txn.ReportErrorDetails("user not found", "NotFound", log.Fields{"user_id": "abc"})
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler(rr, req)
+
+	err = app.RecordEvent("glamplify_unittest_customevent", log.Fields{"aInt": 123})
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, app.Shutdown(context.Background()) == nil)
+
+	spans := recorder.Ended()
+	assert.Equal(t, len(spans), 2)
+
+	txn := spans[0]
+	assert.Equal(t, txn.Name(), "/users")
+	assert.Equal(t, txn.SpanKind(), trace.SpanKindServer)
+	assert.Equal(t, txn.Parent().TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	assert.Equal(t, txn.Status().Code, codes.Error)
+	assert.Assert(t, hasAttribute(txn.Attributes(), attribute.Int("user_count", 3)))
+	assert.Assert(t, hasAttribute(txn.Attributes(), attribute.Int("http.status_code", 500)))
+
+	assert.Equal(t, len(txn.Events()), 1)
+	assert.Assert(t, hasAttribute(txn.Events()[0].Attributes, attribute.String("error.class", "NotFound")))
+	assert.Assert(t, hasAttribute(txn.Events()[0].Attributes, attribute.String("user_id", "abc")))
+
+	event := spans[1]
+	assert.Equal(t, event.Name(), "glamplify_unittest_customevent")
+	assert.Assert(t, hasAttribute(event.Attributes(), attribute.Int("aInt", 123)))
+}
+
+func hasAttribute(attributes []attribute.KeyValue, expected attribute.KeyValue) bool {
+	for _, kv := range attributes {
+		if kv == expected {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBackend_OpenTelemetry_Segments(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	app, err := monitor.NewApplication("Glamplify-Unit-Tests", func(conf *monitor.Config) {
+		conf.Backend = monitor.NewOpenTelemetryBackend(func(conf *monitor.OpenTelemetryConfig) {
+			conf.TracerProvider = provider
+			conf.Propagator = propagation.TraceContext{}
+		})
+	})
+	assert.Assert(t, err == nil, err)
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: monitor.NewRoundTripper(nil)}
+
+	_, handler := app.WrapHTTPHandler("/users", func(w http.ResponseWriter, r *http.Request) {
+		txn, err := monitor.TxnFromRequest(w, r)
+		assert.Assert(t, err == nil, err)
+
+		txn.StartDatastoreSegment("postgresql", "users", "select", "SELECT * FROM users WHERE id = $1").End()
+
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := client.Do(req.WithContext(r.Context()))
+		assert.Assert(t, err == nil, err)
+		resp.Body.Close()
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/users", nil))
+
+	spans := recorder.Ended()
+	assert.Equal(t, len(spans), 3)
+
+	datastore, external, txn := spans[0], spans[1], spans[2]
+	assert.Equal(t, datastore.Name(), "select users")
+	assert.Equal(t, datastore.SpanKind(), trace.SpanKindClient)
+	assert.Equal(t, datastore.Parent().SpanID(), txn.SpanContext().SpanID())
+	assert.Assert(t, hasAttribute(datastore.Attributes(), attribute.String("db.statement", "SELECT * FROM users WHERE id = $1")))
+
+	assert.Equal(t, external.Name(), "HTTP GET")
+	assert.Equal(t, external.Parent().SpanID(), txn.SpanContext().SpanID())
+	assert.Assert(t, hasAttribute(external.Attributes(), attribute.Int("http.status_code", http.StatusOK)))
+
+	// the service called continues the trace from the external segment
+	assert.Assert(t, strings.Contains(traceparent, external.SpanContext().SpanID().String()), traceparent)
+}
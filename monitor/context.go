@@ -6,7 +6,6 @@ import (
 	"net/http"
 
 	"github.com/cultureamp/glamplify/log"
-	newrelic "github.com/newrelic/go-agent"
 )
 
 type key int
@@ -26,6 +25,12 @@ func TxnFromRequest(w http.ResponseWriter, r *http.Request) (*Transaction, error
 
 // TxnFromContext gets the current Transaction from the given context
 func TxnFromContext(ctx context.Context) (*Transaction, error) {
+	return findTxn(ctx, true)
+}
+
+// findTxn gets the current Transaction from the given context, logging (if the Application has logging on) when a
+// lambda call doesn't have one and logMissing is set
+func findTxn(ctx context.Context, logMissing bool) (*Transaction, error) {
 
 	// 1. First try and get the CA txn from the context. It will be there for HTTP wrapped methods,
 	// but not for serverless/lambda ones
@@ -42,9 +47,15 @@ func TxnFromContext(ctx context.Context) (*Transaction, error) {
 		return nil, err
 	}
 
-	// 3. So likely a serverless/lambda call, so get the NR txn from the ctx
+	// 3. So likely a serverless/lambda call. Only a Backend that wraps the handler itself (eg. nrlambda) starts a txn
+	// we don't know about, otherwise the handler started ours (see lambdaHandler.startTxn) so there isn't one.
+	backend, ok := handler.app.backend.(contextBackend)
+	if !ok {
+		return nil, errors.New("no transaction found")
+	}
+
 	txnName = handler.functionName
-	impl := newrelic.FromContext(ctx)
+	impl := backend.TransactionFromContext(ctx)
 	if impl != nil {
 		// A bit yuck - we need to create a CA txn here after the fact because NR created one invisibly to us...
 		txn = &Transaction{
@@ -60,7 +71,9 @@ func TxnFromContext(ctx context.Context) (*Transaction, error) {
 
 	// No transaction!
 	err = errors.New("no transaction found")
-	handler.app.logError("Call app.StartTransaction() to create a new transaction.", err)
+	if logMissing {
+		handler.app.logError("Call app.StartTransaction() to create a new transaction.", err)
+	}
 	return nil, err
 }
 
@@ -2,8 +2,12 @@ package monitor
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/log/logtest"
 	"gotest.tools/assert"
 )
 
@@ -26,3 +30,105 @@ func TestContext_Fail(t *testing.T) {
 	assert.Assert(t, txn == nil, txn)
 	assert.Assert(t, err != nil, err)
 }
+
+// lambdaBackend starts a transaction of its own for every invoke, like nrlambda
+type lambdaBackend struct {
+	NoopBackend
+}
+
+func (backend *lambdaBackend) TransactionFromContext(ctx context.Context) BackendTransaction {
+	return &noopTransaction{}
+}
+
+func TestContext_Lambda_BackendTxn(t *testing.T) {
+	handler := &lambdaHandler{app: Application{backend: &lambdaBackend{}}, functionName: "handler"}
+	ctx := handler.addToContext(context.TODO())
+
+	txn, err := TxnFromContext(ctx)
+	assert.Assert(t, err == nil, err)
+	assert.Equal(t, txn.name, "handler")
+}
+
+func TestContext_Lambda_NoBackendTxn(t *testing.T) {
+	// the handler starts transactions for backends other than New Relic, so there's nothing more to look for
+	handler := &lambdaHandler{app: Application{backend: NewNoopBackend()}, functionName: "handler"}
+	ctx := handler.addToContext(context.TODO())
+
+	txn, err := TxnFromContext(ctx)
+	assert.Assert(t, txn == nil, txn)
+	assert.Error(t, err, "no transaction found")
+}
+
+// missingLambdaBackend wraps the handler itself, but hasn't started a transaction
+type missingLambdaBackend struct {
+	NoopBackend
+}
+
+func (backend *missingLambdaBackend) TransactionFromContext(ctx context.Context) BackendTransaction {
+	return nil
+}
+
+func TestContext_Lambda_MissingTxn(t *testing.T) {
+	logger, recorder := logtest.NewLogger(gcontext.RequestScopedFields{})
+	app := Application{backend: &missingLambdaBackend{}}
+	app.conf.Logging = true
+	app.conf.logger = &monitorLogger{logger: logger}
+
+	handler := &lambdaHandler{app: app, functionName: "handler"}
+	ctx := handler.addToContext(context.TODO())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the RoundTripper looks for a transaction on every request, so doesn't log that there isn't one
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err := NewRoundTripper(nil).RoundTrip(req)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, len(recorder.Entries()), 0)
+
+	_, err = TxnFromContext(ctx)
+	assert.Error(t, err, "no transaction found")
+	recorder.AssertLogged(t, "monitor_error")
+}
+
+// externalRecorder remembers the response given to its external segment
+type externalRecorder struct {
+	noopTransaction
+	status int
+	ended  bool
+}
+
+func (txn *externalRecorder) StartExternalSegment(req *http.Request) TxnSegment {
+	return &recordedSegment{txn: txn}
+}
+
+type recordedSegment struct {
+	txn *externalRecorder
+}
+
+func (segment *recordedSegment) SetResponse(resp *http.Response) {
+	segment.txn.status = resp.StatusCode
+}
+
+func (segment *recordedSegment) End() {
+	segment.txn.ended = true
+}
+
+func TestContext_RoundTripper_Response(t *testing.T) {
+	impl := &externalRecorder{}
+	ctx := context.WithValue(context.TODO(), txnContextKey, &Transaction{impl: impl})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	resp, err := NewRoundTripper(nil).RoundTrip(req)
+	assert.NilError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, impl.status, http.StatusTeapot)
+	assert.Assert(t, impl.ended)
+}
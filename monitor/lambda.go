@@ -14,6 +14,9 @@ type lambdaHandler struct {
 	impl lambda.Handler
 
 	app Application
+	// startTxn is set when the Backend doesn't wrap the handler itself (like nrlambda), so each invoke starts a
+	// Transaction of its own
+	startTxn bool
 
 	functionName    string
 	functionVersion string
@@ -49,7 +52,16 @@ func (handler *lambdaHandler) Invoke(ctx context.Context, payload []byte) ([]byt
 	// Add the CA application to the ctx so that we can get it later inside "Invoke"
 	ctx = handler.app.addToContext(ctx)
 
+	var txn *Transaction
+	if handler.startTxn {
+		ctx, txn = handler.app.startTransaction(ctx, handler.functionName, nil, nil)
+		defer txn.End()
+	}
+
 	result, err := handler.impl.Invoke(ctx, payload)
+	if txn != nil && err != nil {
+		txn.ReportError(err)
+	}
 
 	var tout interface{}
 	if handler.app.conf.Logging {
@@ -70,12 +82,7 @@ func (handler *lambdaHandler) Invoke(ctx context.Context, payload []byte) ([]byt
 
 // Start should be used in place of lambda.Start use app.Start(handler)
 func (app Application) Start(handler interface{}) {
-	// 1. First wrap the handler with NewRelic
-	nr := nrlambda.Wrap(handler, app.impl)
-	// 2. Then wrap that with CultureAmp
-	ca := app.wrapLambda(nr)
-	// 3. Start the handler
-	lambda.StartHandler(ca)
+	app.StartHandler(lambda.NewHandler(handler))
 }
 
 // Start should be used in place of lambda.Start use Start(handler, app)
@@ -85,10 +92,13 @@ func Start(handler interface{}, app *Application) {
 
 // StartHandler should be used in place of lambda.StartHandler use app.StartHandler(handler)
 func (app Application) StartHandler(handler lambda.Handler) {
-	// 1. First wrap the handler with NewRelic
-	nr := nrlambda.WrapHandler(handler, app.impl)
+	// 1. First wrap the handler with NewRelic (if that's the Backend)
+	nr, ok := app.backend.(*newRelicBackend)
+	if ok {
+		handler = nrlambda.WrapHandler(handler, nr.impl)
+	}
 	// 2. Then wrap that with CultureAmp
-	ca := app.wrapLambdaHandler(nr)
+	ca := app.wrapLambda(handler, !ok)
 	// 3. Start the handler
 	lambda.StartHandler(ca)
 }
@@ -98,11 +108,12 @@ func StartHandler(handler lambda.Handler, app *Application) {
 	app.StartHandler(handler)
 }
 
-func (app Application) wrapLambda(handler lambda.Handler) lambda.Handler {
+func (app Application) wrapLambda(handler lambda.Handler, startTxn bool) lambda.Handler {
 
 	return &lambdaHandler{
 		impl:            handler,
 		app:             app,
+		startTxn:        startTxn,
 		functionName:    lambdacontext.FunctionName,
 		functionVersion: lambdacontext.FunctionVersion,
 		logGroupName:    lambdacontext.LogGroupName,
@@ -111,10 +122,6 @@ func (app Application) wrapLambda(handler lambda.Handler) lambda.Handler {
 	}
 }
 
-func (app Application) wrapLambdaHandler(handler interface{}) lambda.Handler {
-	return app.wrapLambda(lambda.NewHandler(handler))
-}
-
 func (handler *lambdaHandler) addToContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, handlerContextKey, handler)
 }
@@ -31,10 +31,19 @@ func NewFromRequest(r *http.Request, fields ...log.Fields) *Logger {
 	return NewFromCtx(r.Context(), fields...)
 }
 
+// NewWitCustomWriter creates a *Logger that writes to writer instead of New Relic, eg. a logtest.Recorder in tests.
+func NewWitCustomWriter(rsFields gcontext.RequestScopedFields, writer log.Writer, fields ...log.Fields) *Logger {
+	return newLoggerWithWriter(rsFields, writer, fields...)
+}
+
 func newLogger(rsFields gcontext.RequestScopedFields, fields ...log.Fields) *Logger {
+	return newLoggerWithWriter(rsFields, defaultWriter(), fields...)
+}
 
-	writer := newWriter()
-	coreLogger := log.NewWitCustomWriter(rsFields, writer, fields...)
+func newLoggerWithWriter(rsFields gcontext.RequestScopedFields, writer log.Writer, fields ...log.Fields) *Logger {
+
+	// skip our own methods, so the caller (if enabled) is the code calling this Logger
+	coreLogger := log.NewWitCustomWriter(rsFields, writer, fields...).AddCallerSkip(1)
 
 	logger := &Logger{
 		coreLogger: coreLogger,
@@ -82,6 +91,9 @@ func (logger Logger) Fatal(event string, err error, fields ...log.Fields) {
 // Event method uses expressive syntax format: logger.Event("event_name").Fields(fields...).Info("message")
 func (logger Logger) Event(event string) *Segment {
 
+	// the Segment methods add a frame between the caller and the Logger
+	logger.coreLogger = logger.coreLogger.AddCallerSkip(1)
+
 	return &Segment{
 		logger: logger,
 		event: event,
@@ -1,10 +1,15 @@
 package monitor
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"runtime"
+	"strings"
 	gcontext "github.com/cultureamp/glamplify/context"
 	"github.com/cultureamp/glamplify/log"
+	"gotest.tools/assert"
 	"os"
 	"testing"
 )
@@ -81,3 +86,29 @@ func Test_Monitor_Fatal(t *testing.T) {
 }
 
 
+
+func Test_Monitor_Caller(t *testing.T) {
+	defer log.SetCallerEnabled(false)
+	log.SetCallerEnabled(true)
+
+	memBuffer := &bytes.Buffer{}
+	writer := log.NewWriter(func(conf *log.WriterConfig) {
+		conf.Output = memBuffer
+		conf.Encoder = log.JSONEncoder{}
+	})
+	logger := newLoggerWithWriter(rsFields, writer)
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("monitor_info")
+	assertCaller(t, memBuffer.String(), line+1)
+
+	memBuffer.Reset()
+	_, _, line, _ = runtime.Caller(0)
+	logger.Event("monitor_info").Fields(log.Fields{"name": "foo"}).Info("info message")
+	assertCaller(t, memBuffer.String(), line+1)
+}
+
+func assertCaller(t *testing.T, msg string, line int) {
+	find := fmt.Sprintf(`"caller":{"file":"monitor/logger_test.go","function":"monitor.Test_Monitor_Caller","line":%d}`, line)
+	assert.Assert(t, strings.Contains(msg, find), "Expected '%s' in '%s'", find, msg)
+}
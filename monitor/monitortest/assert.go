@@ -0,0 +1,108 @@
+package monitortest
+
+import (
+	"reflect"
+	"testing"
+)
+
+// AssertTransaction fails the test unless a transaction with name was started, and returns the latest one
+func (recorder *Recorder) AssertTransaction(t testing.TB, name string) Transaction {
+	t.Helper()
+
+	found := recorder.FindTransaction(name)
+	if len(found) == 0 {
+		t.Fatalf("expected transaction '%s' to be started, but it was not. started: %v", name, recorder.transactionNames())
+		return Transaction{}
+	}
+	return found[len(found)-1]
+}
+
+// AssertNoTransaction fails the test if a transaction with name was started
+func (recorder *Recorder) AssertNoTransaction(t testing.TB, name string) {
+	t.Helper()
+
+	if found := recorder.FindTransaction(name); len(found) > 0 {
+		t.Errorf("expected transaction '%s' not to be started, but it was %d time(s)", name, len(found))
+	}
+}
+
+// AssertEvent fails the test unless a custom event of eventType was recorded, and returns the latest one
+func (recorder *Recorder) AssertEvent(t testing.TB, eventType string) Event {
+	t.Helper()
+
+	found := recorder.FindEvent(eventType)
+	if len(found) == 0 {
+		t.Fatalf("expected event '%s' to be recorded, but it was not. recorded: %v", eventType, recorder.eventTypes())
+		return Event{}
+	}
+	return found[len(found)-1]
+}
+
+// AssertNoEvent fails the test if a custom event of eventType was recorded
+func (recorder *Recorder) AssertNoEvent(t testing.TB, eventType string) {
+	t.Helper()
+
+	if found := recorder.FindEvent(eventType); len(found) > 0 {
+		t.Errorf("expected event '%s' not to be recorded, but it was %d time(s)", eventType, len(found))
+	}
+}
+
+// AssertAttribute fails the test unless the transaction has the attribute key with the expected value
+func AssertAttribute(t testing.TB, txn Transaction, key string, expected interface{}) {
+	t.Helper()
+
+	actual, ok := txn.Attributes[key]
+	if !ok {
+		t.Errorf("expected attribute '%s' in transaction '%s', but it was missing. attributes: %v", key, txn.Name, txn.Attributes)
+		return
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected attribute '%s' to be %v in transaction '%s', but it was %v", key, expected, txn.Name, actual)
+	}
+}
+
+// AssertEventField fails the test unless the custom event has the field key with the expected value
+func AssertEventField(t testing.TB, event Event, key string, expected interface{}) {
+	t.Helper()
+
+	actual, ok := event.Fields[key]
+	if !ok {
+		t.Errorf("expected '%s' in event '%s', but it was missing. fields: %v", key, event.Type, event.Fields)
+		return
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected '%s' to be %v in event '%s', but it was %v", key, expected, event.Type, actual)
+	}
+}
+
+// AssertError fails the test unless an error with message was reported on the transaction, and returns it
+func AssertError(t testing.TB, txn Transaction, message string) Error {
+	t.Helper()
+
+	var messages []string
+	for _, err := range txn.Errors {
+		if err.Err.Error() == message {
+			return err
+		}
+		messages = append(messages, err.Err.Error())
+	}
+
+	t.Errorf("expected error '%s' in transaction '%s', but it was not reported. reported: %v", message, txn.Name, messages)
+	return Error{}
+}
+
+func (recorder *Recorder) transactionNames() []string {
+	var names []string
+	for _, txn := range recorder.Transactions() {
+		names = append(names, txn.Name)
+	}
+	return names
+}
+
+func (recorder *Recorder) eventTypes() []string {
+	var types []string
+	for _, event := range recorder.Events() {
+		types = append(types, event.Type)
+	}
+	return types
+}
@@ -0,0 +1,289 @@
+// Package monitortest records transactions and custom events in memory so tests can assert on what a handler
+// monitored, instead of sending it to New Relic.
+//
+//	app, recorder := monitortest.NewApplication()
+//	_, handler := app.WrapHTTPHandler("/users", usersHandler)
+//	...
+//	txn := recorder.AssertTransaction(t, "/users")
+//	monitortest.AssertAttribute(t, txn, "user_count", 3)
+//	recorder.AssertEvent(t, "user_created")
+package monitortest
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cultureamp/glamplify/log"
+	"github.com/cultureamp/glamplify/monitor"
+)
+
+// Transaction is a transaction as the Backend saw it
+type Transaction struct {
+	Name string
+	// Web is true for a web request transaction (ie. it has a response)
+	Web bool
+
+	Attributes map[string]interface{}
+	Errors     []Error
+	Segments   []Segment
+
+	// StatusCode is the status of the response, or 0 if nothing was written
+	StatusCode int
+	Ended      bool
+}
+
+// Error is an error reported on a Transaction
+type Error struct {
+	Err    error
+	Class  string
+	Fields log.Fields
+}
+
+// Segment is part of a Transaction, eg. txn.StartSegment("load_users"). Datastore segments are named
+// "operation collection" (eg. "select users") and external segments "METHOD host" (eg. "GET example.com").
+type Segment struct {
+	Name  string
+	Ended bool
+
+	// Product, Collection, Operation and Query are set for datastore segments
+	Product    string
+	Collection string
+	Operation  string
+	Query      string
+
+	// URL is set for external segments
+	URL string
+}
+
+// Event is a custom event sent with Application.RecordEvent
+type Event struct {
+	Type   string
+	Fields log.Fields
+}
+
+// Recorder is a monitor.Backend that keeps every transaction and custom event in memory. It is safe for concurrent
+// use.
+type Recorder struct {
+	mutex        sync.Mutex
+	transactions []*transaction
+	events       []Event
+}
+
+// NewRecorder creates a new, empty Recorder. Use it with conf.Backend = recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// NewApplication creates a monitor.Application that sends to a new Recorder
+func NewApplication(configure ...func(*monitor.Config)) (*monitor.Application, *Recorder) {
+	recorder := NewRecorder()
+
+	configure = append(configure, func(conf *monitor.Config) {
+		conf.Backend = recorder
+	})
+	app, _ := monitor.NewApplication("monitortest", configure...)
+	return app, recorder
+}
+
+func (recorder *Recorder) StartTransaction(ctx context.Context, name string, w http.ResponseWriter, r *http.Request) (context.Context, monitor.BackendTransaction) {
+	txn := &transaction{
+		recorder: recorder,
+		w:        w,
+		record:   Transaction{Name: name, Web: w != nil, Attributes: map[string]interface{}{}},
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.transactions = append(recorder.transactions, txn)
+	return ctx, txn
+}
+
+func (recorder *Recorder) RecordCustomEvent(eventType string, fields log.Fields) error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.events = append(recorder.events, Event{Type: eventType, Fields: fields.Merge()})
+	return nil
+}
+
+func (recorder *Recorder) WaitForConnection(timeout time.Duration) error {
+	return nil
+}
+
+func (recorder *Recorder) Shutdown(timeout time.Duration) error {
+	return nil
+}
+
+// Transactions returns a copy of every transaction started, oldest first
+func (recorder *Recorder) Transactions() []Transaction {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	transactions := make([]Transaction, len(recorder.transactions))
+	for i, txn := range recorder.transactions {
+		transactions[i] = txn.copy()
+	}
+	return transactions
+}
+
+// Events returns a copy of every custom event recorded, oldest first
+func (recorder *Recorder) Events() []Event {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	events := make([]Event, len(recorder.events))
+	copy(events, recorder.events)
+	return events
+}
+
+// Reset forgets every transaction and event recorded so far, eg. between sub tests
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.transactions = nil
+	recorder.events = nil
+}
+
+// FindTransaction returns the transactions with name, oldest first
+func (recorder *Recorder) FindTransaction(name string) []Transaction {
+	var found []Transaction
+	for _, txn := range recorder.Transactions() {
+		if txn.Name == name {
+			found = append(found, txn)
+		}
+	}
+	return found
+}
+
+// FindEvent returns the custom events of eventType, oldest first
+func (recorder *Recorder) FindEvent(eventType string) []Event {
+	var found []Event
+	for _, event := range recorder.Events() {
+		if event.Type == eventType {
+			found = append(found, event)
+		}
+	}
+	return found
+}
+
+// transaction is a monitor.BackendTransaction that updates its record under the Recorder's mutex
+type transaction struct {
+	recorder *Recorder
+	w        http.ResponseWriter
+	header   http.Header
+	record   Transaction
+}
+
+func (txn *transaction) Header() http.Header {
+	if txn.w != nil {
+		return txn.w.Header()
+	}
+
+	txn.recorder.mutex.Lock()
+	defer txn.recorder.mutex.Unlock()
+
+	if txn.header == nil {
+		txn.header = http.Header{}
+	}
+	return txn.header
+}
+
+func (txn *transaction) Write(bytes []byte) (int, error) {
+	txn.WriteHeader(http.StatusOK)
+	if txn.w == nil {
+		return len(bytes), nil
+	}
+	return txn.w.Write(bytes)
+}
+
+func (txn *transaction) WriteHeader(statusCode int) {
+	txn.recorder.mutex.Lock()
+	first := txn.record.StatusCode == 0
+	if first {
+		txn.record.StatusCode = statusCode
+	}
+	txn.recorder.mutex.Unlock()
+
+	if first && txn.w != nil {
+		txn.w.WriteHeader(statusCode)
+	}
+}
+
+func (txn *transaction) AddAttribute(key string, value interface{}) error {
+	txn.recorder.mutex.Lock()
+	defer txn.recorder.mutex.Unlock()
+
+	txn.record.Attributes[key] = value
+	return nil
+}
+
+func (txn *transaction) NoticeError(err error, class string, fields log.Fields) error {
+	txn.recorder.mutex.Lock()
+	defer txn.recorder.mutex.Unlock()
+
+	txn.record.Errors = append(txn.record.Errors, Error{Err: err, Class: class, Fields: fields.Merge()})
+	return nil
+}
+
+func (txn *transaction) StartSegment(name string) monitor.TxnSegment {
+	return txn.startSegment(Segment{Name: name})
+}
+
+func (txn *transaction) StartDatastoreSegment(product string, collection string, operation string, query string) monitor.TxnSegment {
+	return txn.startSegment(Segment{
+		Name:       operation + " " + collection,
+		Product:    product,
+		Collection: collection,
+		Operation:  operation,
+		Query:      query,
+	})
+}
+
+func (txn *transaction) StartExternalSegment(req *http.Request) monitor.TxnSegment {
+	return txn.startSegment(Segment{Name: req.Method + " " + req.URL.Host, URL: req.URL.String()})
+}
+
+func (txn *transaction) startSegment(record Segment) monitor.TxnSegment {
+	txn.recorder.mutex.Lock()
+	defer txn.recorder.mutex.Unlock()
+
+	txn.record.Segments = append(txn.record.Segments, record)
+	return &segment{txn: txn, index: len(txn.record.Segments) - 1}
+}
+
+func (txn *transaction) End() error {
+	txn.recorder.mutex.Lock()
+	defer txn.recorder.mutex.Unlock()
+
+	txn.record.Ended = true
+	return nil
+}
+
+// copy returns the record without sharing its maps and slices. Call it with the Recorder's mutex held.
+func (txn *transaction) copy() Transaction {
+	record := txn.record
+
+	record.Attributes = make(map[string]interface{}, len(txn.record.Attributes))
+	for k, v := range txn.record.Attributes {
+		record.Attributes[k] = v
+	}
+	record.Errors = append([]Error(nil), txn.record.Errors...)
+	record.Segments = append([]Segment(nil), txn.record.Segments...)
+	return record
+}
+
+type segment struct {
+	txn   *transaction
+	index int
+}
+
+func (segment *segment) End() {
+	segment.txn.recorder.mutex.Lock()
+	defer segment.txn.recorder.mutex.Unlock()
+
+	segment.txn.record.Segments[segment.index].Ended = true
+}
@@ -0,0 +1,168 @@
+package monitortest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cultureamp/glamplify/log"
+	"github.com/cultureamp/glamplify/monitor"
+	"gotest.tools/assert"
+)
+
+// fakeT records failures instead of failing the real test
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func usersHandler(w http.ResponseWriter, r *http.Request) {
+	txn, err := monitor.TxnFromRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	txn.AddAttributes(log.Fields{"user_count": 3, "password": "secret"})
+	txn.ReportErrorDetails("user not found", "NotFound", log.Fields{"user_id": "abc"})
+
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte("not found"))
+}
+
+func Test_Recorder_Transaction(t *testing.T) {
+	defer log.SetRedactor(nil)
+	log.SetRedactor(log.NewRedactor())
+
+	app, recorder := NewApplication()
+
+	_, handler := app.WrapHTTPHandler("/users", usersHandler)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	handler(rr, req)
+
+	// the response still gets written
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+	assert.Equal(t, rr.Body.String(), "not found")
+
+	txn := recorder.AssertTransaction(t, "/users")
+	assert.Assert(t, txn.Web)
+	assert.Assert(t, txn.Ended)
+	assert.Equal(t, txn.StatusCode, http.StatusNotFound)
+	AssertAttribute(t, txn, "user_count", 3)
+	AssertAttribute(t, txn, "password", "[REDACTED]")
+
+	err := AssertError(t, txn, "user not found")
+	assert.Equal(t, err.Class, "NotFound")
+	assert.DeepEqual(t, err.Fields, log.Fields{"user_id": "abc"})
+
+	recorder.AssertNoTransaction(t, "/accounts")
+}
+
+func Test_Recorder_Events(t *testing.T) {
+	app, recorder := NewApplication()
+
+	fields := log.Fields{"count": 2}
+	err := app.RecordEvent("user_created", fields)
+	assert.Assert(t, err == nil, err)
+	fields["count"] = 3 // later changes don't affect what was recorded
+
+	event := recorder.AssertEvent(t, "user_created")
+	AssertEventField(t, event, "count", 2)
+	recorder.AssertNoEvent(t, "user_deleted")
+
+	recorder.Reset()
+	assert.Assert(t, len(recorder.Events()) == 0)
+	assert.Assert(t, len(recorder.Transactions()) == 0)
+}
+
+func Test_Recorder_Segments(t *testing.T) {
+	app, recorder := NewApplication()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	client := &http.Client{Transport: monitor.NewRoundTripper(nil)}
+
+	_, handler := app.WrapHTTPHandler("/users", func(w http.ResponseWriter, r *http.Request) {
+		txn, err := monitor.TxnFromRequest(w, r)
+		assert.Assert(t, err == nil, err)
+
+		txn.StartSegment("load_users").End()
+		txn.StartDatastoreSegment("Postgres", "users", "select", "SELECT * FROM users WHERE id = $1").End()
+		txn.StartSegment("still_running")
+
+		req, _ := http.NewRequest("GET", server.URL+"/accounts", nil)
+		resp, err := client.Do(req.WithContext(r.Context()))
+		assert.Assert(t, err == nil, err)
+		resp.Body.Close()
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/users", nil))
+
+	// requests without a Transaction aren't timed
+	resp, err := client.Get(server.URL)
+	assert.Assert(t, err == nil, err)
+	resp.Body.Close()
+
+	txn := recorder.AssertTransaction(t, "/users")
+	assert.DeepEqual(t, txn.Segments, []Segment{
+		{Name: "load_users", Ended: true},
+		{Name: "select users", Ended: true, Product: "Postgres", Collection: "users", Operation: "select", Query: "SELECT * FROM users WHERE id = $1"},
+		{Name: "still_running"},
+		{Name: "GET " + strings.TrimPrefix(server.URL, "http://"), Ended: true, URL: server.URL + "/accounts"},
+	})
+}
+
+func Test_Recorder_Failures(t *testing.T) {
+	app, recorder := NewApplication()
+	app.RecordEvent("user_created", log.Fields{"count": 2})
+
+	fake := &fakeT{}
+	recorder.AssertTransaction(fake, "/users")
+	recorder.AssertNoEvent(fake, "user_created")
+	AssertEventField(fake, recorder.AssertEvent(fake, "user_created"), "count", 3)
+	AssertAttribute(fake, Transaction{Name: "/users"}, "count", 3)
+	AssertError(fake, Transaction{Name: "/users"}, "bad")
+
+	assert.DeepEqual(t, fake.failures, []string{
+		"expected transaction '/users' to be started, but it was not. started: []",
+		"expected event 'user_created' not to be recorded, but it was 1 time(s)",
+		"expected 'count' to be 3 in event 'user_created', but it was 2",
+		"expected attribute 'count' in transaction '/users', but it was missing. attributes: map[]",
+		"expected error 'bad' in transaction '/users', but it was not reported. reported: []",
+	})
+}
+
+func Test_Recorder_Concurrent(t *testing.T) {
+	app, recorder := NewApplication()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, handler := app.WrapHTTPHandler("/users", usersHandler)
+			handler(httptest.NewRecorder(), httptest.NewRequest("GET", "/users", nil))
+			app.RecordEvent("user_created", log.Fields{"count": 2})
+			recorder.Transactions()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, len(recorder.FindTransaction("/users")), 10)
+	assert.Equal(t, len(recorder.FindEvent("user_created")), 10)
+	assert.Assert(t, app.Shutdown(context.Background()) == nil)
+}
@@ -0,0 +1,192 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/cultureamp/glamplify/log"
+	newrelic "github.com/newrelic/go-agent"
+)
+
+// newRelicBackend sends to New Relic using their go agent. It is the default Backend.
+type newRelicBackend struct {
+	impl   newrelic.Application
+	logger *connectLogger
+}
+
+func newNewRelicBackend(name string, conf Config) (*newRelicBackend, error) {
+	cfg := newrelic.NewConfig(name, conf.License)
+	cfg.Enabled = conf.Enabled // useful to turn on/off in test/dev vs production accounts
+	cfg.License = conf.License
+	cfg.CustomInsightsEvents.Enabled = true // otherwise custom events won't fire
+	cfg.ErrorCollector.Enabled = true
+	cfg.ErrorCollector.CaptureEvents = true
+	cfg.HighSecurity = false // HighSecurity blocks sending custom events
+	cfg.Labels = conf.Labels // camp, environment, data classification, etc
+	cfg.RuntimeSampler.Enabled = true
+	cfg.ServerlessMode.Enabled = conf.ServerlessMode
+	cfg.TransactionTracer.Enabled = true
+	cfg.Utilization.DetectAWS = true
+	cfg.Utilization.DetectDocker = true
+
+	// for now we turn off DistributedTracing because it is too expensive
+	cfg.DistributedTracer.Enabled = false
+
+	logger := &connectLogger{Logger: nopLogger{}}
+	if conf.logger != nil {
+		logger.Logger = conf.logger
+	}
+	cfg.Logger = logger
+
+	impl, err := newrelic.NewApplication(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &newRelicBackend{impl: impl, logger: logger}, nil
+}
+
+func (backend *newRelicBackend) StartTransaction(ctx context.Context, name string, w http.ResponseWriter, r *http.Request) (context.Context, BackendTransaction) {
+	impl := backend.impl.StartTransaction(name, w, r)
+	return newrelic.NewContext(ctx, impl), &newRelicTransaction{impl: impl}
+}
+
+func (backend *newRelicBackend) TransactionFromContext(ctx context.Context) BackendTransaction {
+	impl := newrelic.FromContext(ctx)
+	if impl == nil {
+		return nil
+	}
+	return &newRelicTransaction{impl: impl}
+}
+
+func (backend *newRelicBackend) RecordCustomEvent(eventType string, fields log.Fields) error {
+	// NewRelic has limits on number and size of entries
+	// https://docs.newrelic.com/docs/insights/insights-data-sources/custom-data/insights-custom-data-requirements-limits
+	// However, if you pass in a string entry longer than 255 it fails "siliently"!!!!!
+	if ok, err := fields.ValidateNewRelic(); !ok {
+		return err
+	}
+	return backend.impl.RecordCustomEvent(eventType, fields)
+}
+
+func (backend *newRelicBackend) WaitForConnection(timeout time.Duration) error {
+	// the agent only tries to connect again after 15s or more, so once an attempt has failed don't wait for the next
+	if err := backend.logger.connectFailure(); err != nil {
+		if backend.impl.WaitForConnection(0) == nil {
+			return nil
+		}
+		return err
+	}
+	return backend.impl.WaitForConnection(timeout)
+}
+
+func (backend *newRelicBackend) Shutdown(timeout time.Duration) error {
+	backend.impl.Shutdown(timeout)
+	return nil
+}
+
+// newRelicTransaction wraps a newrelic.Transaction, which is also the http.ResponseWriter
+type newRelicTransaction struct {
+	impl newrelic.Transaction
+}
+
+func (txn *newRelicTransaction) Header() http.Header {
+	return txn.impl.Header()
+}
+
+func (txn *newRelicTransaction) Write(bytes []byte) (int, error) {
+	return txn.impl.Write(bytes)
+}
+
+func (txn *newRelicTransaction) WriteHeader(statusCode int) {
+	txn.impl.WriteHeader(statusCode)
+}
+
+func (txn *newRelicTransaction) AddAttribute(key string, value interface{}) error {
+	return txn.impl.AddAttribute(key, value)
+}
+
+func (txn *newRelicTransaction) NoticeError(err error, class string, fields log.Fields) error {
+	if class == "" && len(fields) == 0 {
+		return txn.impl.NoticeError(err)
+	}
+	return txn.impl.NoticeError(newrelic.Error{
+		Message:    err.Error(),
+		Class:      class,
+		Attributes: fields,
+	})
+}
+
+func (txn *newRelicTransaction) StartSegment(name string) TxnSegment {
+	return &newRelicSegment{end: newrelic.StartSegment(txn.impl, name).End}
+}
+
+func (txn *newRelicTransaction) StartDatastoreSegment(product string, collection string, operation string, query string) TxnSegment {
+	segment := &newrelic.DatastoreSegment{
+		StartTime:          newrelic.StartSegmentNow(txn.impl),
+		Product:            newrelic.DatastoreProduct(product),
+		Collection:         collection,
+		Operation:          operation,
+		ParameterizedQuery: query,
+	}
+	return &newRelicSegment{end: segment.End}
+}
+
+func (txn *newRelicTransaction) StartExternalSegment(req *http.Request) TxnSegment {
+	return &newRelicExternalSegment{impl: newrelic.StartExternalSegment(txn.impl, req)}
+}
+
+func (txn *newRelicTransaction) End() error {
+	return txn.impl.End()
+}
+
+// newRelicSegment ends any of the newrelic segment types
+type newRelicSegment struct {
+	end func() error
+}
+
+func (segment *newRelicSegment) End() {
+	segment.end()
+}
+
+// newRelicExternalSegment is an ExternalSegment, so NR records the status of the outgoing request
+type newRelicExternalSegment struct {
+	impl *newrelic.ExternalSegment
+}
+
+func (segment *newRelicExternalSegment) SetResponse(resp *http.Response) {
+	segment.impl.Response = resp
+}
+
+func (segment *newRelicExternalSegment) End() {
+	segment.impl.End()
+}
+
+// connectLogger passes everything the agent logs on to Logger, and remembers the last time it failed to connect
+type connectLogger struct {
+	newrelic.Logger
+	failure atomic.Value
+}
+
+func (logger *connectLogger) Warn(msg string, context map[string]interface{}) {
+	if msg == "application connect failure" {
+		logger.failure.Store(fmt.Errorf("connect failed: %v", context["error"]))
+	}
+	logger.Logger.Warn(msg, context)
+}
+
+func (logger *connectLogger) connectFailure() error {
+	err, _ := logger.failure.Load().(error)
+	return err
+}
+
+// nopLogger discards everything, like the agent does when no Logger is configured
+type nopLogger struct{}
+
+func (nopLogger) Error(msg string, context map[string]interface{}) {}
+func (nopLogger) Warn(msg string, context map[string]interface{})  {}
+func (nopLogger) Info(msg string, context map[string]interface{})  {}
+func (nopLogger) Debug(msg string, context map[string]interface{}) {}
+func (nopLogger) DebugEnabled() bool                               { return false }
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cultureamp/glamplify/log"
+	newrelic "github.com/newrelic/go-agent"
+	"gotest.tools/assert"
+)
+
+// neverConnects is an agent that is still trying to connect when the timeout passes
+type neverConnects struct {
+	newrelic.Application
+}
+
+func (impl neverConnects) WaitForConnection(timeout time.Duration) error {
+	time.Sleep(timeout)
+	return errors.New("timed out")
+}
+
+func Test_NewRelic_ConnectFailure(t *testing.T) {
+	logger := &connectLogger{Logger: nopLogger{}}
+	backend := &newRelicBackend{impl: neverConnects{}, logger: logger}
+
+	err := backend.WaitForConnection(10 * time.Millisecond)
+	assert.Error(t, err, "timed out")
+
+	// once an attempt has failed, don't wait for the agent's next attempt
+	logger.Warn("application connect failure", map[string]interface{}{"error": "connection refused"})
+
+	start := time.Now()
+	err = backend.WaitForConnection(time.Hour)
+	assert.Error(t, err, "connect failed: connection refused")
+	assert.Assert(t, time.Since(start) < time.Second, time.Since(start))
+}
+
+// slowBackend takes delay (or the timeout, if less) to connect, and records the timeouts it was given
+type slowBackend struct {
+	NoopBackend
+	delay time.Duration
+
+	mutex    sync.Mutex
+	timeouts []time.Duration
+}
+
+func (backend *slowBackend) record(timeout time.Duration) {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+	backend.timeouts = append(backend.timeouts, timeout)
+}
+
+func (backend *slowBackend) WaitForConnection(timeout time.Duration) error {
+	backend.record(timeout)
+	if backend.delay < timeout {
+		time.Sleep(backend.delay)
+		return errors.New("connect failed")
+	}
+	time.Sleep(timeout)
+	return errors.New("timed out")
+}
+
+func (backend *slowBackend) Shutdown(timeout time.Duration) error {
+	backend.record(timeout)
+	return nil
+}
+
+func Test_Shutdown_SharedDeadline(t *testing.T) {
+	backend := &slowBackend{delay: 150 * time.Millisecond}
+	app := Application{backend: backend}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := app.Shutdown(ctx)
+	assert.Assert(t, err != nil && strings.Contains(err.Error(), "not connected"), err)
+	assert.Assert(t, time.Since(start) < time.Second, time.Since(start))
+
+	// waiting to connect used up most of the deadline, so Shutdown only gets what's left of it
+	assert.Equal(t, len(backend.timeouts), 2)
+	assert.Assert(t, backend.timeouts[0] <= 200*time.Millisecond, backend.timeouts[0])
+	assert.Assert(t, backend.timeouts[1] <= 50*time.Millisecond, backend.timeouts[1])
+}
+
+func Test_Shutdown_DefaultDeadline(t *testing.T) {
+	backend := &slowBackend{delay: 100 * time.Millisecond}
+	app := Application{backend: backend}
+
+	err := app.Shutdown(context.Background())
+	assert.Assert(t, err != nil && strings.Contains(err.Error(), "not connected"), err)
+
+	assert.Equal(t, len(backend.timeouts), 2)
+	assert.Assert(t, backend.timeouts[0] <= defaultWaitTimeout, backend.timeouts[0])
+	assert.Assert(t, backend.timeouts[1] <= defaultWaitTimeout-100*time.Millisecond, backend.timeouts[1])
+}
+
+func Test_Application_RegisterShutdown(t *testing.T) {
+	backend := &slowBackend{}
+	app := &Application{backend: backend}
+
+	app.RegisterShutdown()
+	defer log.UnregisterShutdownHook(fmt.Sprintf("monitor.Application(%p)", app))
+
+	// slowBackend never connects, so the hook fails, but the backend is still shut down
+	err := log.RunShutdownHooks(context.Background())
+	assert.Assert(t, err != nil && strings.Contains(err.Error(), "monitor.Application("), err)
+	assert.Equal(t, len(backend.timeouts), 2)
+}
+
+func Test_NewRelic_ExternalSegmentResponse(t *testing.T) {
+	txn := &newRelicTransaction{}
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+
+	segment, ok := txn.StartExternalSegment(req).(ExternalSegment)
+	assert.Assert(t, ok)
+
+	resp := &http.Response{StatusCode: http.StatusNotFound}
+	segment.SetResponse(resp)
+	assert.Equal(t, segment.(*newRelicExternalSegment).impl.Response, resp)
+	segment.End()
+}
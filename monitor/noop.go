@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/cultureamp/glamplify/log"
+)
+
+// NoopBackend doesn't send anything anywhere. Transactions still write to the response, so handlers behave the same.
+// Useful in tests and local development, eg. conf.Backend = monitor.NewNoopBackend()
+type NoopBackend struct{}
+
+// NewNoopBackend creates a Backend that doesn't send anything
+func NewNoopBackend() *NoopBackend {
+	return &NoopBackend{}
+}
+
+func (backend *NoopBackend) StartTransaction(ctx context.Context, name string, w http.ResponseWriter, r *http.Request) (context.Context, BackendTransaction) {
+	return ctx, &noopTransaction{responseWriter: newResponseWriter(w)}
+}
+
+func (backend *NoopBackend) RecordCustomEvent(eventType string, fields log.Fields) error {
+	return nil
+}
+
+func (backend *NoopBackend) WaitForConnection(timeout time.Duration) error {
+	return nil
+}
+
+func (backend *NoopBackend) Shutdown(timeout time.Duration) error {
+	return nil
+}
+
+type noopTransaction struct {
+	*responseWriter
+}
+
+func (txn *noopTransaction) AddAttribute(key string, value interface{}) error {
+	return nil
+}
+
+func (txn *noopTransaction) NoticeError(err error, class string, fields log.Fields) error {
+	return nil
+}
+
+func (txn *noopTransaction) StartSegment(name string) TxnSegment {
+	return noopSegment{}
+}
+
+func (txn *noopTransaction) StartDatastoreSegment(product string, collection string, operation string, query string) TxnSegment {
+	return noopSegment{}
+}
+
+func (txn *noopTransaction) StartExternalSegment(req *http.Request) TxnSegment {
+	return noopSegment{}
+}
+
+func (txn *noopTransaction) End() error {
+	return nil
+}
+
+type noopSegment struct{}
+
+func (segment noopSegment) End() {}
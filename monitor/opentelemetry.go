@@ -0,0 +1,213 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cultureamp/glamplify/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// InstrumentationName is the name of the OpenTelemetry Tracer that spans are created with
+	InstrumentationName = "github.com/cultureamp/glamplify/monitor"
+
+	errorClassKey = "error.class"
+)
+
+// OpenTelemetryConfig contains the OpenTelemetryBackend settings
+type OpenTelemetryConfig struct {
+
+	// TracerProvider creates the spans. Defaults to the global otel.GetTracerProvider(), which is a no-op until you
+	// set up an SDK (eg. with an OTLP exporter) and call otel.SetTracerProvider.
+	TracerProvider trace.TracerProvider
+
+	// Propagator reads the trace context of incoming web requests, so they continue the caller's trace, and adds it
+	// to outgoing ones (see StartExternalSegment). Defaults to the global otel.GetTextMapPropagator().
+	Propagator propagation.TextMapPropagator
+}
+
+// OpenTelemetryBackend sends transactions as OpenTelemetry spans. Custom events don't have an equivalent, so each is
+// sent as a span of its own, named after the event type.
+type OpenTelemetryBackend struct {
+	conf   OpenTelemetryConfig
+	tracer trace.Tracer
+}
+
+// NewOpenTelemetryBackend creates a Backend that sends to the configured TracerProvider, eg.
+// conf.Backend = monitor.NewOpenTelemetryBackend()
+func NewOpenTelemetryBackend(configure ...func(*OpenTelemetryConfig)) *OpenTelemetryBackend {
+	conf := OpenTelemetryConfig{
+		TracerProvider: otel.GetTracerProvider(),
+		Propagator:     otel.GetTextMapPropagator(),
+	}
+
+	for _, config := range configure {
+		config(&conf)
+	}
+
+	return &OpenTelemetryBackend{
+		conf:   conf,
+		tracer: conf.TracerProvider.Tracer(InstrumentationName),
+	}
+}
+
+func (backend *OpenTelemetryBackend) StartTransaction(ctx context.Context, name string, w http.ResponseWriter, r *http.Request) (context.Context, BackendTransaction) {
+	opts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindInternal)}
+	if r != nil {
+		ctx = backend.conf.Propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+		opts = []trace.SpanStartOption{
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest("", name, r)...),
+		}
+	}
+
+	ctx, span := backend.tracer.Start(ctx, name, opts...)
+	return ctx, &openTelemetryTransaction{
+		responseWriter: newResponseWriter(w),
+		backend:        backend,
+		ctx:            ctx,
+		span:           span,
+		web:            r != nil,
+	}
+}
+
+func (backend *OpenTelemetryBackend) RecordCustomEvent(eventType string, fields log.Fields) error {
+	_, span := backend.tracer.Start(context.Background(), eventType, trace.WithAttributes(toAttributes(fields)...))
+	span.End()
+	return nil
+}
+
+func (backend *OpenTelemetryBackend) WaitForConnection(timeout time.Duration) error {
+	// the exporter connects when it first sends, so there is nothing to wait for
+	return nil
+}
+
+func (backend *OpenTelemetryBackend) Shutdown(timeout time.Duration) error {
+	// the SDK TracerProvider batches spans, so send them now. It's the caller's to shut down though, as they created it.
+	flusher, ok := backend.conf.TracerProvider.(interface{ ForceFlush(context.Context) error })
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return flusher.ForceFlush(ctx)
+}
+
+type openTelemetryTransaction struct {
+	*responseWriter
+	backend *OpenTelemetryBackend
+	ctx     context.Context
+	span    trace.Span
+	web     bool
+}
+
+func (txn *openTelemetryTransaction) AddAttribute(key string, value interface{}) error {
+	txn.span.SetAttributes(toAttribute(key, value))
+	return nil
+}
+
+func (txn *openTelemetryTransaction) NoticeError(err error, class string, fields log.Fields) error {
+	attributes := toAttributes(fields)
+	if class != "" {
+		attributes = append(attributes, attribute.String(errorClassKey, class))
+	}
+
+	txn.span.RecordError(err, trace.WithAttributes(attributes...))
+	txn.span.SetStatus(codes.Error, err.Error())
+	return nil
+}
+
+func (txn *openTelemetryTransaction) StartSegment(name string) TxnSegment {
+	_, span := txn.backend.tracer.Start(txn.ctx, name)
+	return openTelemetrySegment{span: span}
+}
+
+func (txn *openTelemetryTransaction) StartDatastoreSegment(product string, collection string, operation string, query string) TxnSegment {
+	attributes := []attribute.KeyValue{
+		semconv.DBSystemKey.String(product),
+		semconv.DBSQLTableKey.String(collection),
+		semconv.DBOperationKey.String(operation),
+	}
+	if query != "" {
+		attributes = append(attributes, semconv.DBStatementKey.String(query))
+	}
+
+	_, span := txn.backend.tracer.Start(txn.ctx, operation+" "+collection,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+	return openTelemetrySegment{span: span}
+}
+
+func (txn *openTelemetryTransaction) StartExternalSegment(req *http.Request) TxnSegment {
+	ctx, span := txn.backend.tracer.Start(txn.ctx, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(req)...),
+	)
+	txn.backend.conf.Propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return openTelemetryExternalSegment{openTelemetrySegment{span: span}}
+}
+
+func (txn *openTelemetryTransaction) End() error {
+	if txn.web && txn.status != 0 {
+		txn.span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(txn.status)...)
+		if code, msg := semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(txn.status, trace.SpanKindServer); code == codes.Error {
+			txn.span.SetStatus(code, msg)
+		}
+	}
+	txn.span.End()
+	return nil
+}
+
+type openTelemetrySegment struct {
+	span trace.Span
+}
+
+func (segment openTelemetrySegment) End() {
+	segment.span.End()
+}
+
+// openTelemetryExternalSegment is an ExternalSegment, so the span has the status of the outgoing request
+type openTelemetryExternalSegment struct {
+	openTelemetrySegment
+}
+
+func (segment openTelemetryExternalSegment) SetResponse(resp *http.Response) {
+	segment.span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(resp.StatusCode)...)
+	segment.span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(resp.StatusCode, trace.SpanKindClient))
+}
+
+func toAttributes(fields log.Fields) []attribute.KeyValue {
+	attributes := make([]attribute.KeyValue, 0, len(fields))
+	for k, v := range fields {
+		attributes = append(attributes, toAttribute(k, v))
+	}
+	return attributes
+}
+
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case fmt.Stringer:
+		return attribute.Stringer(key, v)
+	}
+	return attribute.String(key, fmt.Sprint(value))
+}
@@ -0,0 +1,40 @@
+package monitor
+
+import "net/http"
+
+// roundTripper times each request made with a Transaction in its ctx as an external segment
+type roundTripper struct {
+	original http.RoundTripper
+}
+
+// NewRoundTripper wraps original (or http.DefaultTransport if nil) so that every request made with a ctx holding a
+// Transaction (see TxnFromContext) is timed as an external segment of it, eg.
+//
+//	client := &http.Client{Transport: monitor.NewRoundTripper(nil)}
+//	req, _ := http.NewRequestWithContext(r.Context(), "GET", url, nil)
+//	resp, err := client.Do(req)
+func NewRoundTripper(original http.RoundTripper) http.RoundTripper {
+	if original == nil {
+		original = http.DefaultTransport
+	}
+	return &roundTripper{original: original}
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// most requests aren't made in a transaction, so don't log that there isn't one
+	txn, err := findTxn(req.Context(), false)
+	if err != nil || txn == nil {
+		return rt.original.RoundTrip(req)
+	}
+
+	// a RoundTripper mustn't change the request, and the segment may add headers
+	req = req.Clone(req.Context())
+	segment := txn.StartExternalSegment(req)
+	defer segment.End()
+
+	resp, err := rt.original.RoundTrip(req)
+	if external, ok := segment.(ExternalSegment); ok && resp != nil {
+		external.SetResponse(resp)
+	}
+	return resp, err
+}
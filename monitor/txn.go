@@ -2,15 +2,15 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/cultureamp/glamplify/log"
-	newrelic "github.com/newrelic/go-agent"
 )
 
 // Transaction is a wrapper over the underlying implementation
 type Transaction struct {
-	impl    newrelic.Transaction
+	impl    BackendTransaction
 	app     *Application
 	name    string
 	logging bool
@@ -26,6 +26,7 @@ func (txn Transaction) GetApplication() *Application {
 func (txn Transaction) AddAttributes(fields log.Fields) error {
 
 	var err error
+	fields = log.GetRedactor().Redact(fields)
 	for k, v := range fields {
 		err = txn.impl.AddAttribute(k, v)
 		if err != nil {
@@ -37,15 +38,29 @@ func (txn Transaction) AddAttributes(fields log.Fields) error {
 }
 
 func (txn Transaction) ReportError(err error) error {
-	return txn.impl.NoticeError(err)
+	return txn.impl.NoticeError(err, "", nil)
 }
 
 func (txn Transaction) ReportErrorDetails(msg string, class string, fields log.Fields) error {
-	return txn.impl.NoticeError(newrelic.Error{
-		Message:    msg,
-		Class:      class,
-		Attributes: fields,
-	})
+	return txn.impl.NoticeError(errors.New(msg), class, log.GetRedactor().Redact(fields))
+}
+
+// StartSegment starts timing part of the transaction, eg. defer txn.StartSegment("load_users").End()
+func (txn Transaction) StartSegment(name string) TxnSegment {
+	return txn.impl.StartSegment(name)
+}
+
+// StartDatastoreSegment starts timing a database call, eg.
+// defer txn.StartDatastoreSegment("Postgres", "users", "select", "SELECT * FROM users WHERE id = $1").End()
+// The query is optional, and should have its parameters replaced by placeholders.
+func (txn Transaction) StartDatastoreSegment(product string, collection string, operation string, query string) TxnSegment {
+	return txn.impl.StartDatastoreSegment(product, collection, operation, query)
+}
+
+// StartExternalSegment starts timing an outgoing web request. It may add headers to req so the service it calls can
+// continue the trace, so call it before sending req. NewRoundTripper does this for every request.
+func (txn Transaction) StartExternalSegment(req *http.Request) TxnSegment {
+	return txn.impl.StartExternalSegment(req)
 }
 
 // End closes the current transaction
@@ -74,11 +89,6 @@ func (txn Transaction) WriteHeader(statusCode int) {
 	txn.impl.WriteHeader(statusCode)
 }
 
-func (txn *Transaction) addToHTTPContext(req *http.Request) *http.Request {
-	ctx := txn.addToContext(req.Context())
-	return req.WithContext(ctx)
-}
-
 func (txn *Transaction) addToContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, txnContextKey, txn)
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/cultureamp/glamplify/log"
 	"github.com/cultureamp/glamplify/monitor"
+	"github.com/cultureamp/glamplify/monitor/monitortest"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -29,7 +30,7 @@ func TestTxn_AddAttribute_Server_Success(t *testing.T) {
 	req, err := http.NewRequest("GET", "/", nil)
 	h.ServeHTTP(rr, req)
 
-	app.Shutdown()
+	app.Shutdown(context.Background())
 }
 
 func addAttribute(w http.ResponseWriter, r *http.Request) {
@@ -64,7 +65,7 @@ func TestTxn_NoticeError_Server_Success(t *testing.T) {
 	req = req.WithContext(ctx)
 	h.ServeHTTP(rr, req)
 
-	app.Shutdown()
+	app.Shutdown(context.Background())
 }
 
 func reportError(w http.ResponseWriter, r *http.Request) {
@@ -82,3 +83,59 @@ func reportError(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+
+func TestTxn_Recorded(t *testing.T) {
+	app, recorder := monitortest.NewApplication()
+
+	_, handler := app.WrapHTTPHandler("/", addAttribute)
+	h := http.HandlerFunc(handler)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "t", t))
+	h.ServeHTTP(rr, req)
+
+	_, handler = app.WrapHTTPHandler("/error", reportError)
+	h = http.HandlerFunc(handler)
+	req, _ = http.NewRequest("GET", "/error", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "t", t))
+	h.ServeHTTP(rr, req)
+
+	txn := recorder.AssertTransaction(t, "/")
+	monitortest.AssertAttribute(t, txn, "aString", "hello world")
+	monitortest.AssertAttribute(t, txn, "aInt", 123)
+
+	txn = recorder.AssertTransaction(t, "/error")
+	monitortest.AssertError(t, txn, "standard error message")
+	err := monitortest.AssertError(t, txn, "detailed error")
+	assert.Equal(t, err.Class, "txn_test")
+}
+
+func TestTxn_Segments_NewRelic(t *testing.T) {
+	app, err := monitor.NewApplication("Glamplify-Unit-Tests", func(conf *monitor.Config) {
+		conf.Enabled = false
+	})
+	assert.Assert(t, err == nil, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	client := &http.Client{Transport: monitor.NewRoundTripper(nil)}
+
+	_, handler := app.WrapHTTPHandler("/", func(w http.ResponseWriter, r *http.Request) {
+		txn, err := monitor.TxnFromRequest(w, r)
+		assert.Assert(t, err == nil, err)
+
+		txn.StartSegment("load_users").End()
+		txn.StartDatastoreSegment("Postgres", "users", "select", "").End()
+
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := client.Do(req.WithContext(r.Context()))
+		assert.Assert(t, err == nil, err)
+		resp.Body.Close()
+	})
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	handler(rr, req)
+	assert.Equal(t, rr.Code, http.StatusOK)
+}
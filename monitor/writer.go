@@ -2,60 +2,157 @@ package monitor
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
-	"github.com/cultureamp/glamplify/log"
 	"io/ioutil"
+	"math"
+	systemLog "log"
 	"net/http"
 	"os"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/cultureamp/glamplify/log"
+)
+
+const (
+	// maxPayloadBytes is the largest body the Log API accepts
+	// https://docs.newrelic.com/docs/logs/new-relic-logs/log-api/introduction-log-api#limits
+	maxPayloadBytes = 1000000
 )
 
-// writerConfig for setting initial values for Monitor Writer
-type writerConfig struct {
-	// license is your New Relic license key.
+// WriterConfig for setting initial values for Monitor Writer
+type WriterConfig struct {
+	// License is your New Relic license key.
 	//
 	// https://docs.newrelic.com/docs/accounts/install-new-relic/account-setup/license-key
-	license string
+	License string
 
-	// URL
+	// Endpoint
 	// US: https://log-api.newrelic.com/log/v1  (default)
 	// EU: https://log-api.eu.newrelic.com/log/v1
-	endpoint string
+	Endpoint string
+
+	// Timeout for each http request to the Log API
+	Timeout time.Duration
+
+	// BufferSize is the number of entries queued before new entries are dropped
+	BufferSize int
+
+	// BatchSize is the maximum number of entries sent in a single request
+	BatchSize int
 
-	// timeout
-	timeout time.Duration
+	// FlushInterval is how long an entry can wait before a partial batch is sent
+	FlushInterval time.Duration
+
+	// MaxRetries is how many times a batch is retried after a 429, 5xx or network error
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry, doubling for each subsequent retry. A Retry-After from the Log
+	// API is used instead, up to RetryBackoff << MaxRetries.
+	RetryBackoff time.Duration
+
+	// OnError is called (from the writer's go routine) when a batch could not be sent. Defaults to the standard library logger.
+	OnError func(err error, entries int)
 }
 
 // FieldWriter sends logging output to NR as per https://docs.newrelic.com/docs/logs/new-relic-logs/log-api/introduction-log-api
+// Entries are batched, gzipped and sent from a single background go routine. Call Flush or Shutdown before the process exits.
 type FieldWriter struct {
-	mutex  sync.Mutex
-	config writerConfig
+	config WriterConfig
+	client *http.Client
+
+	entries chan []byte
+	flushes chan flushRequest
+	stop    chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+
+	// enqueuing is held for reading while an entry is queued, so Shutdown can wait for those in flight before the
+	// background go routine drains the queue for the last time
+	enqueuing sync.RWMutex
+
+	// ctx is cancelled when Shutdown gives up, so a send in progress (or waiting to retry) stops too
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	stopOnce sync.Once
+
+	sent    uint64
+	failed  uint64
+	dropped uint64
 }
 
-// newWriter creates a new FieldWriter. The optional configure func lets you set values on the underlying standard writer.
-// Useful for CLI apps that want to direct logging to a file or stderr
-// eg. SetOutput
-func newWriter(configure ...func(*writerConfig)) *FieldWriter { // https://dave.cheney.net/2014/10/17/functional-options-for-friendly-apis
-	conf := writerConfig{
-		license:  os.Getenv("NEW_RELIC_LICENSE_KEY"),
-		endpoint: getEnvOrDefaultString("NEW_RELIC_LOG_ENDPOINT", "https://log-api.newrelic.com/log/v1"),
-		timeout:  time.Second * time.Duration(getEnvOrDefaultInt("NEW_RELIC_TIMEOUT", 5)),
+var (
+	internalWriter     *FieldWriter
+	internalWriterOnce sync.Once
+)
+
+// NewWriter creates a new FieldWriter and starts its background go routine. The optional configure func lets you set
+// the license, endpoint and batching behaviour.
+func NewWriter(configure ...func(*WriterConfig)) *FieldWriter { // https://dave.cheney.net/2014/10/17/functional-options-for-friendly-apis
+	conf := WriterConfig{
+		License:       os.Getenv("NEW_RELIC_LICENSE_KEY"),
+		Endpoint:      getEnvOrDefaultString("NEW_RELIC_LOG_ENDPOINT", "https://log-api.newrelic.com/log/v1"),
+		Timeout:       time.Second * time.Duration(getEnvOrDefaultInt("NEW_RELIC_TIMEOUT", 5)),
+		BufferSize:    10000,
+		BatchSize:     1000,
+		FlushInterval: 5 * time.Second,
+		MaxRetries:    3,
+		RetryBackoff:  500 * time.Millisecond,
+		OnError: func(err error, entries int) {
+			systemLog.Printf("failed to send %d log entries to new relic. err: %s", entries, err.Error())
+		},
 	}
 
 	for _, config := range configure {
 		config(&conf)
 	}
 
+	if conf.BufferSize <= 0 {
+		conf.BufferSize = 1
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 1
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	writer := &FieldWriter{
-		config: conf,
+		config:  conf,
+		client:  &http.Client{Timeout: conf.Timeout},
+		entries: make(chan []byte, conf.BufferSize),
+		flushes: make(chan flushRequest),
+		stop:    make(chan struct{}),
+		quit:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
 	}
 
+	go writer.run()
+
 	return writer
 }
 
+// FlushLogs sends any log entries queued by monitor.Logger, waiting until they are sent or the ctx is done.
+func FlushLogs(ctx context.Context) error {
+	return defaultWriter().Flush(ctx)
+}
+
+func defaultWriter() *FieldWriter {
+	internalWriterOnce.Do(func() {
+		internalWriter = NewWriter()
+	})
+	return internalWriter
+}
+
 func (writer *FieldWriter) WriteFields(system log.Fields, fields ...log.Fields) {
 	merged := log.Fields{}
 	properties := merged.Merge(fields...)
@@ -63,37 +160,313 @@ func (writer *FieldWriter) WriteFields(system log.Fields, fields ...log.Fields)
 		system[log.Properties] = properties
 	}
 
-	json := system.ToSnakeCase().ToJson()
+	// the Log API has its own payload limit (see maxPayloadBytes), so entries aren't truncated here
+	json := log.JSONEncoder{MaxValueLength: -1, MaxLineLength: -1}.Encode(system.ToSnakeCase())
+	if len(json) == 0 {
+		atomic.AddUint64(&writer.dropped, 1)
+		return
+	}
+
+	writer.enqueuing.RLock()
+	defer writer.enqueuing.RUnlock()
 
-	go post(writer.config, json)
+	select {
+	case <-writer.stop:
+		atomic.AddUint64(&writer.dropped, 1)
+		return
+	default:
+	}
+
+	select {
+	case writer.entries <- []byte(json):
+	default:
+		atomic.AddUint64(&writer.dropped, 1)
+	}
 }
 
-func post(config writerConfig, jsonStr string) error {
-	// https://docs.newrelic.com/docs/logs/new-relic-logs/log-api/introduction-log-api
-	jsonBytes := []byte(jsonStr)
+// Flush sends every entry queued before the call, waiting until they are sent (or have failed) or the ctx is done. A
+// send still retrying when the ctx is done gives up, and its entries are counted as failed.
+func (writer *FieldWriter) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+
+	select {
+	case writer.flushes <- flushRequest{ctx: ctx, flushed: flushed}:
+	case <-writer.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting new entries, sends everything still queued and stops the background go routine. If the ctx
+// is done first, the send in progress gives up so the go routine still stops.
+func (writer *FieldWriter) Shutdown(ctx context.Context) error {
+	writer.stopOnce.Do(func() {
+		// stop new entries, wait for those already being queued, then let the background go routine send what's left.
+		// Every entry is either sent (or failed) or counted as dropped.
+		close(writer.stop)
+		writer.enqueuing.Lock()
+		close(writer.quit)
+		writer.enqueuing.Unlock()
+	})
+
+	select {
+	case <-writer.done:
+		return nil
+	case <-ctx.Done():
+		writer.cancel()
+		return ctx.Err()
+	}
+}
+
+// Sent returns the number of entries accepted by the Log API
+func (writer *FieldWriter) Sent() uint64 {
+	return atomic.LoadUint64(&writer.sent)
+}
+
+// Failed returns the number of entries that could not be sent after all retries
+func (writer *FieldWriter) Failed() uint64 {
+	return atomic.LoadUint64(&writer.failed)
+}
+
+// Dropped returns the number of entries discarded before sending because the buffer was full, the writer was shut down,
+// or the entry could not fit in a payload
+func (writer *FieldWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&writer.dropped)
+}
+
+func (writer *FieldWriter) run() {
+	defer close(writer.done)
+	defer writer.cancel()
+
+	ticker := time.NewTicker(writer.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := newLogBatch()
+	for {
+		select {
+		case entry := <-writer.entries:
+			writer.add(writer.ctx, batch, entry)
+		case <-ticker.C:
+			writer.send(writer.ctx, batch)
+		case req := <-writer.flushes:
+			ctx, cancel := writer.sendContext(req.ctx)
+			writer.drain(ctx, batch)
+			cancel()
+			close(req.flushed)
+		case <-writer.quit:
+			writer.drain(writer.ctx, batch)
+			return
+		}
+	}
+}
+
+// sendContext returns a ctx that is done when either ctx is, or Shutdown gives up
+func (writer *FieldWriter) sendContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-writer.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func (writer *FieldWriter) drain(ctx context.Context, batch *logBatch) {
+	for {
+		select {
+		case entry := <-writer.entries:
+			writer.add(ctx, batch, entry)
+		default:
+			writer.send(ctx, batch)
+			return
+		}
+	}
+}
+
+func (writer *FieldWriter) add(ctx context.Context, batch *logBatch, entry []byte) {
+	if !batch.fits(entry) {
+		writer.send(ctx, batch)
+	}
+	if !batch.fits(entry) {
+		// on its own it is too big to ever send
+		atomic.AddUint64(&writer.dropped, 1)
+		return
+	}
+
+	batch.add(entry)
+	if batch.count >= writer.config.BatchSize {
+		writer.send(ctx, batch)
+	}
+}
+
+func (writer *FieldWriter) send(ctx context.Context, batch *logBatch) {
+	if batch.count == 0 {
+		return
+	}
+
+	count := batch.count
+	err := writer.post(ctx, batch.payload())
+	batch.reset()
 
-	req, err := http.NewRequest("POST", config.endpoint, bytes.NewBuffer(jsonBytes))
 	if err != nil {
+		atomic.AddUint64(&writer.failed, uint64(count))
+		if writer.config.OnError != nil {
+			writer.config.OnError(err, count)
+		}
+		return
+	}
+	atomic.AddUint64(&writer.sent, uint64(count))
+}
+
+func (writer *FieldWriter) post(ctx context.Context, payload []byte) error {
+	// https://docs.newrelic.com/docs/logs/new-relic-logs/log-api/introduction-log-api
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(payload); err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-license-Key", config.license)
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	backoff := writer.config.RetryBackoff
+	// a Retry-After longer than our own backoff would ever get to would hold up every batch after this one
+	maxWait := maxRetryWait(writer.config.RetryBackoff, writer.config.MaxRetries)
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		var wait time.Duration
+		retry, wait, err = writer.postOnce(ctx, compressed.Bytes())
+		if err == nil || !retry || attempt >= writer.config.MaxRetries {
+			return err
+		}
+
+		if wait <= 0 {
+			wait = backoff
+			if backoff < maxWait {
+				backoff *= 2
+			}
+		}
+		if wait > maxWait {
+			wait = maxWait
+		}
+		if waitErr := sleep(ctx, wait); waitErr != nil {
+			return fmt.Errorf("%v (gave up retrying: %v)", err, waitErr)
+		}
+	}
+}
 
-	var client = &http.Client{
-		Timeout: config.timeout,
+// maxRetryWait is RetryBackoff doubled for each retry, stopping short of overflowing for a large MaxRetries
+func maxRetryWait(backoff time.Duration, retries int) time.Duration {
+	wait := backoff
+	for i := 0; i < retries && wait > 0 && wait <= math.MaxInt64/2; i++ {
+		wait *= 2
+	}
+	return wait
+}
+
+// sleep waits for d, or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	resp, err := client.Do(req)
+}
+
+func (writer *FieldWriter) postOnce(ctx context.Context, body []byte) (bool, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", writer.config.Endpoint, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return false, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-License-Key", writer.config.License)
+
+	resp, err := writer.client.Do(req)
+	if err != nil {
+		// network errors and timeouts are worth another go, unless we've been told to stop
+		return ctx.Err() == nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
-		return nil
+		ioutil.ReadAll(resp.Body)
+		return false, 0, nil
+	}
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	err = errors.New(fmt.Sprintf("bad server response: %d. body: %v", resp.StatusCode, string(respBody)))
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	wait := time.Duration(0)
+	if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+		wait = time.Duration(seconds) * time.Second
+	}
+	return retry, wait, err
+}
+
+// flushRequest asks the writer's go routine to send everything queued, giving up when ctx is done
+type flushRequest struct {
+	ctx     context.Context
+	flushed chan struct{}
+}
+
+// logBatch builds the Log API detailed payload: [{"logs":[{...},{...}]}]
+type logBatch struct {
+	buffer bytes.Buffer
+	count  int
+}
+
+var (
+	batchPrefix = []byte(`[{"logs":[`)
+	batchSuffix = []byte(`]}]`)
+)
+
+func newLogBatch() *logBatch {
+	batch := &logBatch{}
+	batch.reset()
+	return batch
+}
+
+func (batch *logBatch) fits(entry []byte) bool {
+	// +1 for the comma separating entries
+	return batch.buffer.Len()+len(entry)+1+len(batchSuffix) <= maxPayloadBytes
+}
+
+func (batch *logBatch) add(entry []byte) {
+	if batch.count > 0 {
+		batch.buffer.WriteByte(',')
 	}
-	body, err := ioutil.ReadAll(resp.Body)
-	str := string(body)
-	return errors.New(fmt.Sprintf("bad server response: %d. body: %v", resp.StatusCode, str))
+	batch.buffer.Write(entry)
+	batch.count++
+}
+
+func (batch *logBatch) payload() []byte {
+	payload := make([]byte, 0, batch.buffer.Len()+len(batchSuffix))
+	payload = append(payload, batch.buffer.Bytes()...)
+	return append(payload, batchSuffix...)
+}
+
+func (batch *logBatch) reset() {
+	batch.buffer.Reset()
+	batch.buffer.Write(batchPrefix)
+	batch.count = 0
 }
 
 func getEnvOrDefaultString(key string, defaultValue string) string {
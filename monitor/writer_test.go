@@ -1,20 +1,335 @@
 package monitor
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/cultureamp/glamplify/log"
+	"gotest.tools/assert"
 )
 
+type logAPI struct {
+	mutex      sync.Mutex
+	statuses   []int
+	retryAfter string
+	requests   int
+	entries    []map[string]interface{}
+	headers    []http.Header
+	bodySize   []int
+}
+
+func (api *logAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+
+	api.requests++
+	api.headers = append(api.headers, r.Header.Clone())
+
+	status := http.StatusAccepted
+	if len(api.statuses) > 0 {
+		status = api.statuses[0]
+		api.statuses = api.statuses[1:]
+	}
+	if status != http.StatusAccepted {
+		if api.retryAfter != "" {
+			w.Header().Set("Retry-After", api.retryAfter)
+		}
+		w.WriteHeader(status)
+		return
+	}
+
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	body, _ := ioutil.ReadAll(zr)
+	api.bodySize = append(api.bodySize, len(body))
+
+	var payload []struct {
+		Logs []map[string]interface{} `json:"logs"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	for _, p := range payload {
+		api.entries = append(api.entries, p.Logs...)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func newTestWriter(api *logAPI, configure ...func(*WriterConfig)) (*FieldWriter, *httptest.Server) {
+	srv := httptest.NewServer(api)
+
+	writer := NewWriter(append([]func(*WriterConfig){func(config *WriterConfig) {
+		config.Endpoint = srv.URL
+		config.License = "test-license"
+		config.FlushInterval = time.Hour
+		config.RetryBackoff = time.Millisecond
+		config.OnError = nil
+	}}, configure...)...)
+
+	return writer, srv
+}
+
+func Test_Writer_Batches(t *testing.T) {
+	api := &logAPI{}
+	writer, srv := newTestWriter(api)
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	for i := 0; i < 10; i++ {
+		mlog.Info("hello_world", log.Fields{"count": i})
+	}
+
+	err := writer.Flush(context.Background())
+	assert.Assert(t, err == nil, err)
+
+	assert.Assert(t, api.requests == 1, api.requests)
+	assert.Assert(t, len(api.entries) == 10, len(api.entries))
+	assert.Assert(t, api.entries[0]["event"] == "hello_world", api.entries[0])
+	assert.Assert(t, api.headers[0].Get("Content-Encoding") == "gzip")
+	assert.Assert(t, api.headers[0].Get("X-License-Key") == "test-license")
+	assert.Assert(t, writer.Sent() == 10, writer.Sent())
+	assert.Assert(t, writer.Failed() == 0, writer.Failed())
+
+	err = writer.Shutdown(context.Background())
+	assert.Assert(t, err == nil, err)
+}
+
+func Test_Writer_BatchSize(t *testing.T) {
+	api := &logAPI{}
+	writer, srv := newTestWriter(api, func(config *WriterConfig) {
+		config.BatchSize = 3
+	})
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	for i := 0; i < 7; i++ {
+		mlog.Info("hello_world")
+	}
+
+	err := writer.Shutdown(context.Background())
+	assert.Assert(t, err == nil, err)
+
+	assert.Assert(t, api.requests == 3, api.requests)
+	assert.Assert(t, len(api.entries) == 7, len(api.entries))
+	assert.Assert(t, writer.Sent() == 7, writer.Sent())
+}
+
+func Test_Writer_PayloadLimit(t *testing.T) {
+	api := &logAPI{}
+	writer, srv := newTestWriter(api)
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	big := strings.Repeat("x", 300000)
+	for i := 0; i < 5; i++ {
+		mlog.Info("big_event", log.Fields{"big": big})
+	}
+	mlog.Info("too_big_event", log.Fields{"big": strings.Repeat("x", maxPayloadBytes)})
+
+	err := writer.Shutdown(context.Background())
+	assert.Assert(t, err == nil, err)
+
+	assert.Assert(t, api.requests == 2, api.requests)
+	for _, size := range api.bodySize {
+		assert.Assert(t, size <= maxPayloadBytes, size)
+	}
+	assert.Assert(t, writer.Sent() == 5, writer.Sent())
+	assert.Assert(t, writer.Dropped() == 1, writer.Dropped())
+}
+
+func Test_Writer_Retry(t *testing.T) {
+	api := &logAPI{statuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}}
+	writer, srv := newTestWriter(api)
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	mlog.Info("hello_world")
+
+	err := writer.Shutdown(context.Background())
+	assert.Assert(t, err == nil, err)
+
+	assert.Assert(t, api.requests == 3, api.requests)
+	assert.Assert(t, writer.Sent() == 1, writer.Sent())
+	assert.Assert(t, writer.Failed() == 0, writer.Failed())
+}
+
+func Test_Writer_RetryAfterCapped(t *testing.T) {
+	api := &logAPI{statuses: []int{http.StatusTooManyRequests}, retryAfter: "3600"}
+	writer, srv := newTestWriter(api)
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	mlog.Info("hello_world")
+
+	// RetryBackoff << MaxRetries is 8ms, not an hour
+	timeout, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := writer.Shutdown(timeout)
+	assert.Assert(t, err == nil, err)
+
+	assert.Assert(t, api.requests == 2, api.requests)
+	assert.Assert(t, writer.Sent() == 1, writer.Sent())
+}
+
+func Test_Writer_ShutdownStopsRetrying(t *testing.T) {
+	api := &logAPI{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	writer, srv := newTestWriter(api, func(config *WriterConfig) {
+		config.RetryBackoff = time.Hour
+	})
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	mlog.Info("hello_world")
+
+	timeout, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := writer.Shutdown(timeout)
+	assert.Equal(t, err, context.DeadlineExceeded)
+
+	// the go routine gives up waiting to retry, rather than sleeping for an hour
+	select {
+	case <-writer.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writer still retrying after Shutdown gave up")
+	}
+	assert.Assert(t, writer.Failed() == 1, writer.Failed())
+}
+
+func Test_Writer_FlushStopsRetrying(t *testing.T) {
+	api := &logAPI{statuses: []int{http.StatusServiceUnavailable}}
+	writer, srv := newTestWriter(api, func(config *WriterConfig) {
+		config.RetryBackoff = time.Hour
+	})
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	mlog.Info("hello_world")
+
+	timeout, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := writer.Flush(timeout)
+	assert.Equal(t, err, context.DeadlineExceeded)
+
+	// the writer is free to send the next batch
+	mlog.Info("hello_again")
+	err = writer.Shutdown(context.Background())
+	assert.Assert(t, err == nil, err)
+	assert.Assert(t, writer.Failed() == 1, writer.Failed())
+	assert.Assert(t, writer.Sent() == 1, writer.Sent())
+}
+
+func Test_Writer_NoRetryOnBadRequest(t *testing.T) {
+	api := &logAPI{statuses: []int{http.StatusForbidden}}
+
+	var failed int
+	writer, srv := newTestWriter(api, func(config *WriterConfig) {
+		config.OnError = func(err error, entries int) {
+			failed += entries
+		}
+	})
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	mlog.Info("hello_world")
+	mlog.Info("hello_world")
+
+	err := writer.Shutdown(context.Background())
+	assert.Assert(t, err == nil, err)
+
+	assert.Assert(t, api.requests == 1, api.requests)
+	assert.Assert(t, writer.Failed() == 2, writer.Failed())
+	assert.Assert(t, failed == 2, failed)
+}
+
+func Test_Writer_RetriesExhausted(t *testing.T) {
+	api := &logAPI{statuses: []int{500, 500, 500}}
+	writer, srv := newTestWriter(api, func(config *WriterConfig) {
+		config.MaxRetries = 2
+	})
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	mlog.Info("hello_world")
+
+	err := writer.Shutdown(context.Background())
+	assert.Assert(t, err == nil, err)
+
+	assert.Assert(t, api.requests == 3, api.requests)
+	assert.Assert(t, writer.Failed() == 1, writer.Failed())
+}
+
+func Test_Writer_DroppedAfterShutdown(t *testing.T) {
+	api := &logAPI{}
+	writer, srv := newTestWriter(api)
+	defer srv.Close()
+
+	writer.Shutdown(context.Background())
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+	mlog.Info("hello_world")
+
+	assert.Assert(t, writer.Dropped() == 1, writer.Dropped())
+	assert.Assert(t, api.requests == 0, api.requests)
+}
+
+func Test_Writer_ShutdownWhileWriting(t *testing.T) {
+	api := &logAPI{}
+	writer, srv := newTestWriter(api)
+	defer srv.Close()
+
+	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				mlog.Info("hello_world")
+			}
+		}()
+	}
+
+	time.Sleep(time.Millisecond)
+	assert.NilError(t, writer.Shutdown(context.Background()))
+	wg.Wait()
+
+	// every entry was either sent before Shutdown returned, or counted as dropped
+	total := writer.Sent() + writer.Failed() + writer.Dropped()
+	assert.Assert(t, total == 400, "sent %d failed %d dropped %d", writer.Sent(), writer.Failed(), writer.Dropped())
+}
+
+func Test_Writer_MaxRetryWait(t *testing.T) {
+	assert.Equal(t, maxRetryWait(time.Second, 3), 8*time.Second)
+	assert.Equal(t, maxRetryWait(time.Second, 0), time.Second)
+
+	// doubling 500ms 100 times would overflow
+	wait := maxRetryWait(500*time.Millisecond, 100)
+	assert.Assert(t, wait > time.Hour, wait)
+}
+
 func Test_Realworld(t *testing.T) {
 	// https://log-api.newrelic.com/log/v1
-	writer := newWriter(func(config *writerConfig) {
-		config.endpoint ="https://log-api.newrelic.com/log/v1"
+	writer := NewWriter(func(config *WriterConfig) {
+		config.Endpoint = "https://log-api.newrelic.com/log/v1"
 	})
 	mlog := log.NewFromCtxWithCustomerWriter(ctx, writer)
 
 	mlog.Info("hello_world2")
-	time.Sleep(2 * time.Second)
-}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	writer.Shutdown(ctx)
+}
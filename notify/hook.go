@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bugsnag/bugsnag-go"
+	"github.com/cultureamp/glamplify/log"
+)
+
+// LogHook is a log.Hook that forwards ERROR and FATAL entries to Bugsnag, so you don't need to call both
+// logger.Error and notifier.Error. The error class is the type of the logged error, the context is the event, and
+// the properties and system values are sent as meta data. FATAL entries are sent synchronously, as the process is
+// about to stop.
+type LogHook struct {
+	notifier *Notifier
+}
+
+// NewLogHook returns a LogHook that sends to notifier, or to the default Notifier if nil
+//
+//	log.AddHook(notify.NewLogHook(notifier))
+func NewLogHook(notifier *Notifier) *LogHook {
+	if notifier == nil {
+		notifier = internal
+	}
+	return &LogHook{notifier: notifier}
+}
+
+// Fire sends ERROR and FATAL entries to Bugsnag. It never vetoes the entry.
+func (hook LogHook) Fire(severity string, event string, system log.Fields, properties log.Fields) bool {
+	if severity != log.ErrorSev && severity != log.FatalSev {
+		return true
+	}
+	if hook.notifier == nil || !hook.notifier.conf.Enabled {
+		return true
+	}
+
+	err, class := entryError(event, system)
+
+	meta := fieldsAsMetaData(properties)
+	for k, v := range system {
+		if k != log.Exception {
+			meta.Add("log", k, v)
+		}
+	}
+
+	rawData := []interface{}{meta, bugsnag.Context{String: event}, bugsnag.SeverityError}
+	if class != "" {
+		rawData = append(rawData, bugsnag.ErrorClass{Name: class})
+	}
+	if severity == log.FatalSev {
+		rawData = append(rawData, bugsnag.Configuration{Synchronous: true})
+	}
+
+	// This can return an error, but we just swallow it here as what can we or a client really do? Try and log it? :)
+	bugsnag.Notify(err, rawData...)
+	return true
+}
+
+// entryError returns the logged error, as best we can from the exception written with the entry, and its type
+func entryError(event string, system log.Fields) (error, string) {
+	exception, ok := system[log.Exception].(log.Fields)
+	if !ok {
+		return errors.New(event), ""
+	}
+
+	message := fmt.Sprint(exception["error"])
+	class, _ := exception["type"].(string)
+	return errors.New(message), class
+}
@@ -0,0 +1,54 @@
+package notify_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/bugsnag/bugsnag-go"
+	gcontext "github.com/cultureamp/glamplify/context"
+	"github.com/cultureamp/glamplify/log"
+	"github.com/cultureamp/glamplify/notify"
+	"gotest.tools/assert"
+)
+
+type hookTestError struct{}
+
+func (hookTestError) Error() string { return "hook test failed" }
+
+func TestNotify_LogHook(t *testing.T) {
+
+	notifier, err := notify.NewNotifier("GlamplifyUnitTests", func(conf *notify.Config) {
+		conf.Enabled = true
+		conf.AppVersion = "1.0.0"
+	})
+	assert.Assert(t, err == nil, err)
+
+	var mutex sync.Mutex
+	var events []*bugsnag.Event
+	bugsnag.OnBeforeNotify(func(event *bugsnag.Event, config *bugsnag.Configuration) error {
+		if event.Context != "hook_test_event" {
+			return nil
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+		// don't send it
+		return errors.New("captured")
+	})
+
+	writer := log.NewWriter(func(conf *log.WriterConfig) { conf.Output = ioutil.Discard })
+	hooked := log.NewWitCustomWriter(gcontext.RequestScopedFields{}, writer).WithHooks(notify.NewLogHook(notifier))
+
+	hooked.Info("hook_test_event")
+	hooked.Error("hook test event", hookTestError{}, log.Fields{"user_name": "mike"})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0].ErrorClass, "notify_test.hookTestError")
+	assert.Equal(t, events[0].Message, "hook test failed")
+	assert.Equal(t, events[0].MetaData["app context"]["user_name"], "mike")
+	assert.Equal(t, events[0].MetaData["log"]["event"], "hook_test_event")
+}
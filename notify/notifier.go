@@ -2,6 +2,7 @@ package notify
 
 import (
 	"context"
+	"fmt"
 	"github.com/bugsnag/bugsnag-go"
 	"github.com/cultureamp/glamplify/helper"
 	"github.com/cultureamp/glamplify/log"
@@ -58,7 +59,7 @@ func NewNotifier(name string, configure ...func(*Config)) (*Notifier, error) {
 		AppVersion:      conf.AppVersion,
 		ReleaseStage:    conf.ReleaseStage,
 		ProjectPackages: conf.ProjectPackages,
-		ParamsFilters:   []string{"password", "pwd"}, // todo - add others
+		ParamsFilters:   []string{"password", "pwd", "secret", "token", "api_key", "authorization", "cookie"}, // request params, our own fields go through log.GetRedactor()
 	}
 
 	if conf.Logging {
@@ -75,6 +76,12 @@ func (notify Notifier) Shutdown() {
 	time.Sleep(waitFORBugsnag)
 }
 
+// RegisterShutdown registers Shutdown as a shutdown hook (see log.RegisterShutdownHook), so pending errors are sent
+// when log.RunShutdownHooks is called or log.Fatal exits
+func (notify *Notifier) RegisterShutdown() {
+	log.RegisterShutdownHook(fmt.Sprintf("notify.Notifier(%p)", notify), log.ShutdownFunc(notify.Shutdown))
+}
+
 // Adds a Bugsnag when used as middleware
 func (notify *Notifier) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -134,6 +141,7 @@ func (notify *Notifier) addToContext(ctx context.Context) context.Context {
 
 func fieldsAsMetaData(fields log.Fields) bugsnag.MetaData {
 	meta := make(bugsnag.MetaData)
+	fields = log.GetRedactor().Redact(fields)
 	for k, v := range fields {
 		meta.Add("app context", k, v)
 	}
@@ -3,12 +3,15 @@ package notify_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/cultureamp/glamplify/log"
 	"github.com/cultureamp/glamplify/notify"
 	"gotest.tools/assert"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNotify_Error_Success(t *testing.T) {
@@ -72,4 +75,19 @@ func rootRequest(w http.ResponseWriter, r *http.Request) {
 	assert.Assert(t, err == nil, err)
 }
 
+func TestNotify_RegisterShutdown(t *testing.T) {
+	notifier, err := notify.NewNotifier("GlamplifyUnitTests", func(conf *notify.Config) {
+		conf.Enabled = false
+	})
+	assert.Assert(t, err == nil, err)
 
+	notifier.RegisterShutdown()
+	defer log.UnregisterShutdownHook(fmt.Sprintf("notify.Notifier(%p)", notifier))
+
+	// Shutdown waits longer than this, so the hook reports it didn't finish
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = log.RunShutdownHooks(ctx)
+	assert.Assert(t, err != nil)
+	assert.Assert(t, strings.Contains(err.Error(), "notify.Notifier("), err)
+}